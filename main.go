@@ -7,12 +7,17 @@ import (
 	"os"
 	"strings"
 
+	"github.com/ToniLommez/Neon_Dream_Runner/pkg/compiler"
 	e "github.com/ToniLommez/Neon_Dream_Runner/pkg/errutils"
 	l "github.com/ToniLommez/Neon_Dream_Runner/pkg/lexer"
 	p "github.com/ToniLommez/Neon_Dream_Runner/pkg/parser"
 	u "github.com/ToniLommez/Neon_Dream_Runner/pkg/utils"
+	"github.com/ToniLommez/Neon_Dream_Runner/pkg/vm"
 )
 
+// useVM runs the scripts on the bytecode vm instead of the tree-walker, chosen by --vm
+var useVM bool
+
 func runFile(path string) error {
 	file, err := os.Open(path)
 	if err != nil {
@@ -32,10 +37,26 @@ func runFile(path string) error {
 	neon.Init(false)
 	neon.Text = strings.Split(string(content), "\n")
 
+	// a script that declares fn main has it called after its top level, unless it called it already
 	_, err = run(string(content), true, &neon)
+	if f, ok := neon.MainFunction(); ok && err == nil {
+		if useVM {
+			_, err = vm.Call(&neon.Main, f, nil, f.Declaration.Name)
+		} else {
+			_, err = neon.Main.Call(f, nil, f.Declaration.Name)
+		}
+	}
+
+	// the program ends with its tasks, unless it already failed
+	if err == nil {
+		err = neon.Wait()
+	} else if n := neon.Running(); n > 0 {
+		e.Deal(e.Error(0, 0, "", e.WARNING, fmt.Sprintf("%d tasks were still running", n)), "")
+	}
 	if err != nil {
+		err = p.Uncaught(err)
 		var fatal error
-		if myErr, ok := err.(e.NeonError); ok {
+		if myErr, ok := err.(e.NeonError); ok && myErr.Line > 0 && myErr.Line <= len(neon.Text) {
 			fatal = e.Deal(err, neon.Text[myErr.Line-1])
 		} else {
 			fatal = e.Deal(err, "")
@@ -72,6 +93,10 @@ func runRepl() error {
 				return nil
 			case "clear":
 				u.ClearScreen()
+			case "aspects":
+				for _, a := range neon.Aspects() {
+					fmt.Println(a)
+				}
 			default:
 				depth, err = run(prompt, false, &neon)
 				if err != nil {
@@ -115,13 +140,24 @@ func run(input string, isFile bool, neon *p.Program) (depth int, err error) {
 	// If correctly parsed save the statement
 	neon.Tokens = neon.TokensBuffer
 	neon.TokensBuffer = nil
-	neon.Main.Statements = statement
 
-	// Evaluate the AST
-	res, err := neon.Main.Interpret()
+	// Bind identifiers to their scopes before running anything
+	statement, err = neon.Resolver.Resolve(statement)
+	warn(neon)
 	if err != nil {
 		return 0, err
 	}
+	// Evaluate the AST
+	var res any
+	if useVM {
+		res, err = vm.Run(compiler.Compile(statement), &neon.Main)
+	} else {
+		neon.Main.Statements = statement
+		res, err = neon.Main.Interpret()
+	}
+	if err != nil {
+		return 0, p.Uncaught(err)
+	}
 
 	if res != nil {
 		tmp := fmt.Sprintf("%v", res)
@@ -133,11 +169,27 @@ func run(input string, isFile bool, neon *p.Program) (depth int, err error) {
 	return 0, nil
 }
 
+func warn(neon *p.Program) {
+	for _, w := range neon.Resolver.Warnings {
+		line := ""
+		if myErr, ok := w.(e.NeonError); ok && !neon.IsLive && myErr.Line-1 < len(neon.Text) {
+			line = neon.Text[myErr.Line-1]
+		}
+		e.Deal(w, line)
+	}
+}
+
 func main() {
-	if len(os.Args) > 2 {
+	args := os.Args[1:]
+	if len(args) > 0 && args[0] == "--vm" {
+		useVM = true
+		args = args[1:]
+	}
+
+	if len(args) > 1 {
 		os.Exit(64) // TODO: better response
-	} else if len(os.Args) == 2 {
-		if err := runFile(os.Args[1]); err != nil {
+	} else if len(args) == 1 {
+		if err := runFile(args[0]); err != nil {
 			fmt.Println(err)
 		}
 	} else {
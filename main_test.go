@@ -0,0 +1,56 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+// output runs a script as `neon` and `neon --vm` do, and gives what it printed, errors included
+func output(t *testing.T, script string, vm bool) string {
+	t.Helper()
+
+	r, w, err := os.Pipe()
+	if err != nil {
+		t.Fatalf("pipe: %v", err)
+	}
+	printed := make(chan string)
+	go func() {
+		b, _ := io.ReadAll(r)
+		printed <- string(b)
+	}()
+
+	stdout, stderr := os.Stdout, os.Stderr
+	os.Stdout, os.Stderr, useVM = w, w, vm
+	defer func() {
+		os.Stdout, os.Stderr, useVM = stdout, stderr, false
+	}()
+
+	if err := runFile(script); err != nil {
+		fmt.Println(err)
+	}
+	w.Close()
+	return <-printed
+}
+
+// Every script in testdata runs on the tree-walker and on the vm, which should print the same
+func TestBackendsAgree(t *testing.T) {
+	scripts, err := filepath.Glob(filepath.Join("testdata", "*.ne"))
+	if err != nil || len(scripts) == 0 {
+		t.Fatalf("no scripts in testdata: %v", err)
+	}
+
+	for _, script := range scripts {
+		t.Run(filepath.Base(script), func(t *testing.T) {
+			tree := output(t, script, false)
+			if tree == "" {
+				t.Fatalf("%s printed nothing", script)
+			}
+			if vm := output(t, script, true); vm != tree {
+				t.Errorf("the vm printed:\n%s\nthe tree-walker printed:\n%s", vm, tree)
+			}
+		})
+	}
+}
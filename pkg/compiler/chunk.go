@@ -0,0 +1,112 @@
+package compiler
+
+import (
+	"fmt"
+
+	p "github.com/ToniLommez/Neon_Dream_Runner/pkg/parser"
+)
+
+type Op byte
+
+const (
+	CONSTANT      Op = iota // push Constants[A]
+	POP                     // drop the top of the stack
+	DUP                     // copy the top of the stack
+	GET                     // push the variable at depth A, slot B, named by Constants[C]
+	ASSIGN                  // store the top in the variable of the Assign at Constants[A]
+	DEFINE                  // declare the LetStmt at Constants[A] with the popped value, push nil
+	UNPACK                  // declare the DestructureStmt at Constants[A] with the popped Tuple, push nil
+	FUNCTION                // declare the compiled Function at Constants[A], push nil
+	CLOSURE                 // push the compiled Function at Constants[A] as a value
+	BINARY                  // apply the operator token at Constants[A] to the two values on top
+	UNARY                   // apply the operator token at Constants[A] to the value on top
+	CAST                    // convert the value below the top to the Type on top, Constants[A] is the operator
+	TRUTHY                  // replace the top with its truthiness
+	JUMP                    // go to A
+	JUMP_IF_FALSE           // pop and go to A when falsy
+	JUMP_IF_TRUE            // pop and go to A when truthy
+	SCOPE                   // open a scope with room for A variables
+	END_SCOPE               // close the innermost scope
+	CALL                    // call the value below the A arguments, Constants[B] is the call token
+	RETURN                  // leave the function with the value on top
+	TUPLE                   // pop A values into a Tuple
+	PUT                     // print the popped value, push nil
+	EVAL                    // evaluate the node at Constants[A] with the tree-walker
+	LOOP                    // enter the loop at Constants[A], a break or continue inside goes where it says
+	END_LOOP                // leave the innermost loop entered by LOOP
+	ITER                    // replace the collection on top with a p.Cursor over it, Constants[A] is the keyword
+	NEXT                    // go to A when the cursor on top is done, otherwise push B values: its value, then its position
+	MATCH                   // go to A unless the value on top matches one of the patterns at Constants[B]
+	INDEX                   // read the position on top of the collection below it, Constants[A] is the PositionAccess
+	MEMBER                  // read the member of the Access at Constants[A] named by Constants[C], go to B with nil when a `?.` finds nil
+	SET_INDEX               // finish the PositionAssign at Constants[A] with the popped value
+	SET_FIELD               // finish the AccessAssign at Constants[A] with the popped value
+	ARRAY                   // pop the size and B values into the ArrayLiteral at Constants[A]
+	SLICE                   // pop B values into the SliceLiteral at Constants[A]
+	MAP                     // pop B keys, each followed by its value, into the MapLiteral at Constants[A]
+)
+
+var opNames = [...]string{"CONSTANT", "POP", "DUP", "GET", "ASSIGN", "DEFINE", "UNPACK", "FUNCTION", "CLOSURE", "BINARY", "UNARY", "CAST", "TRUTHY", "JUMP", "JUMP_IF_FALSE", "JUMP_IF_TRUE", "SCOPE", "END_SCOPE", "CALL", "RETURN", "TUPLE", "PUT", "EVAL", "LOOP", "END_LOOP", "ITER", "NEXT", "MATCH", "INDEX", "MEMBER", "SET_INDEX", "SET_FIELD", "ARRAY", "SLICE", "MAP"}
+
+func (o Op) String() string {
+	return opNames[o]
+}
+
+type Instruction struct {
+	Op Op
+	A  int
+	B  int
+	C  int
+}
+
+type Chunk struct {
+	Code      []Instruction
+	Constants []any
+}
+
+// Loop is where a break and a continue aimed at a loop go, Label is empty for a loop without one
+type Loop struct {
+	Label    string
+	Break    int
+	Continue int
+}
+
+// Function is the compiled body of a `fn` or lambda, the vm wraps it in a p.Function to call it
+type Function struct {
+	Declaration p.FnStmt
+	Chunk       Chunk
+}
+
+func (c *Chunk) emit(op Op, operands ...int) int {
+	i := Instruction{Op: op}
+	if len(operands) > 0 {
+		i.A = operands[0]
+	}
+	if len(operands) > 1 {
+		i.B = operands[1]
+	}
+	if len(operands) > 2 {
+		i.C = operands[2]
+	}
+
+	c.Code = append(c.Code, i)
+	return len(c.Code) - 1
+}
+
+func (c *Chunk) constant(v any) int {
+	c.Constants = append(c.Constants, v)
+	return len(c.Constants) - 1
+}
+
+// patch points the jump at i to the next instruction
+func (c *Chunk) patch(i int) {
+	c.Code[i].A = len(c.Code)
+}
+
+func (c Chunk) String() string {
+	str := ""
+	for i, x := range c.Code {
+		str += fmt.Sprintf("%04d %-13s %d %d %d\n", i, x.Op, x.A, x.B, x.C)
+	}
+	return str
+}
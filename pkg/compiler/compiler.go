@@ -0,0 +1,374 @@
+package compiler
+
+import (
+	l "github.com/ToniLommez/Neon_Dream_Runner/pkg/lexer"
+	p "github.com/ToniLommez/Neon_Dream_Runner/pkg/parser"
+)
+
+// Compile lowers resolved statements to bytecode, every node leaves exactly one
+// value on the stack, so a list of statements ends with the value of the last one
+// like Scope.Interpret does. Nodes without an instruction of their own are kept
+// whole and handed to the tree-walker by EVAL, a break or continue among them
+// reaches the loop through the signal the tree-walker gives.
+func Compile(statements []p.Stmt) *Function {
+	var c Chunk
+	c.statements(statements)
+	c.emit(RETURN)
+	return &Function{Chunk: c}
+}
+
+func compileFunction(d p.FnStmt) *Function {
+	var c Chunk
+
+	// A block body runs straight in the scope of the call
+	if b, ok := d.Body.(p.Block); ok {
+		c.statements(b.Scope.Statements)
+	} else {
+		c.compile(d.Body)
+	}
+	c.emit(RETURN)
+
+	return &Function{Declaration: d, Chunk: c}
+}
+
+func (c *Chunk) statements(statements []p.Stmt) {
+	if len(statements) == 0 {
+		c.emit(CONSTANT, c.constant(nil))
+		return
+	}
+
+	// the hoisted declarations are defined first and leave nil where they are written
+	for _, stmt := range statements {
+		if p.Hoisted(stmt) {
+			c.compile(stmt)
+			c.emit(POP)
+		}
+	}
+
+	for i, stmt := range statements {
+		if i > 0 {
+			c.emit(POP)
+		}
+		if p.Hoisted(stmt) {
+			c.emit(CONSTANT, c.constant(nil))
+			continue
+		}
+		c.compile(stmt)
+	}
+}
+
+func (c *Chunk) operator(op l.Token) int {
+	return c.constant(op)
+}
+
+func (c *Chunk) compile(node any) {
+	switch x := node.(type) {
+	case p.LetStmt:
+		if x.Initializer != nil {
+			c.compile(x.Initializer)
+		} else {
+			c.emit(CONSTANT, c.constant(nil))
+		}
+		c.emit(DEFINE, c.constant(x))
+	case p.DestructureStmt:
+		c.compile(x.Initializer)
+		c.emit(UNPACK, c.constant(x))
+	case p.FnStmt:
+		c.emit(FUNCTION, c.constant(compileFunction(x)))
+	case p.ReturnStmt:
+		if x.Value != nil {
+			c.compile(x.Value)
+		} else {
+			c.emit(CONSTANT, c.constant(nil))
+		}
+		c.emit(RETURN)
+	case p.PutStmt:
+		c.compile(x.Value)
+		c.emit(PUT)
+	case p.ExprStmt:
+		c.compile(x.Expr)
+	case p.WhileStmt:
+		k := c.enter(x.Label, x.Breaks)
+		loop := len(c.Code)
+		c.compile(x.Condition)
+		exit := c.emit(leave(x.Until))
+		c.compile(x.Body)
+		c.emit(POP)
+		next := len(c.Code)
+		if x.Increment != nil {
+			c.compile(x.Increment)
+			c.emit(POP)
+		}
+		c.emit(JUMP, loop)
+		c.patch(exit)
+		c.exit(k, next)
+		c.emit(CONSTANT, c.constant(nil))
+	case p.DoStmt:
+		k := c.enter(x.Label, x.Breaks)
+		loop := len(c.Code)
+		c.compile(x.Body)
+		c.emit(POP)
+		next := len(c.Code)
+		c.compile(x.Condition)
+		if x.Until {
+			c.emit(JUMP_IF_FALSE, loop)
+		} else {
+			c.emit(JUMP_IF_TRUE, loop)
+		}
+		c.exit(k, next)
+		c.emit(CONSTANT, c.constant(nil))
+	case p.LoopStmt:
+		if x.Times == nil {
+			k := c.enter(x.Label, x.Breaks)
+			loop := len(c.Code)
+			c.compile(x.Body)
+			c.emit(POP)
+			c.emit(JUMP, loop)
+			c.exit(k, loop)
+			c.emit(CONSTANT, c.constant(nil))
+			break
+		}
+		c.compile(x.Times)
+		c.emit(ITER, c.operator(x.Keyword))
+		k := c.enter(x.Label, x.Breaks)
+		loop := len(c.Code)
+		done := c.emit(NEXT, 0, 0)
+		c.compile(x.Body)
+		c.emit(POP)
+		c.emit(JUMP, loop)
+		c.patch(done)
+		c.exit(k, loop)
+		c.emit(POP)
+		c.emit(CONSTANT, c.constant(nil))
+	case p.ForInStmt:
+		// every iteration declares the variables in a scope of its own, the position goes first
+		c.compile(x.Collection)
+		c.emit(ITER, c.operator(x.Keyword))
+		k := c.enter(x.Label, x.Breaks)
+		loop := len(c.Code)
+		done := c.emit(NEXT, 0, len(x.Vars))
+		c.emit(SCOPE, x.Slots)
+		for _, v := range x.Vars {
+			v.Initializer = p.Literal{}
+			c.emit(DEFINE, c.constant(v))
+			c.emit(POP)
+		}
+		c.compile(x.Body)
+		c.emit(POP)
+		c.emit(END_SCOPE)
+		c.emit(JUMP, loop)
+		c.patch(done)
+		c.exit(k, loop)
+		c.emit(POP)
+		c.emit(CONSTANT, c.constant(nil))
+	case p.IfStmt:
+		c.branch(x.Condition, x.Then, x.Else)
+	case p.Ternary:
+		c.branch(x.Expression, x.True, x.False)
+	case p.Block:
+		c.emit(SCOPE, x.Slots)
+		c.statements(x.Scope.Statements)
+		c.emit(END_SCOPE)
+	case p.Sequence:
+		c.compile(x.Left)
+		c.emit(POP)
+		c.compile(x.Right)
+	case p.Assign:
+		c.compile(x.Value)
+		c.emit(ASSIGN, c.constant(x))
+	case p.Logic:
+		c.compile(x.Left)
+		c.emit(TRUTHY)
+		c.emit(DUP)
+		var exit int
+		if x.Operator.Type == l.AND_LOGIC {
+			exit = c.emit(JUMP_IF_FALSE)
+		} else {
+			exit = c.emit(JUMP_IF_TRUE)
+		}
+		c.emit(POP)
+		c.compile(x.Right)
+		c.emit(TRUTHY)
+		c.patch(exit)
+	case p.Equality:
+		c.binary(x.Left, x.Operator, x.Right)
+	case p.Comparison:
+		c.binary(x.Left, x.Operator, x.Right)
+	case p.Bitshift:
+		c.binary(x.Left, x.Operator, x.Right)
+	case p.Bitwise:
+		c.binary(x.Left, x.Operator, x.Right)
+	case p.Term:
+		c.binary(x.Left, x.Operator, x.Right)
+	case p.Factor:
+		c.binary(x.Left, x.Operator, x.Right)
+	case p.Power:
+		c.binary(x.Left, x.Operator, x.Right)
+	case p.Unary:
+		c.compile(x.Right)
+		c.emit(UNARY, c.operator(x.Operator))
+	case p.Cast:
+		c.compile(x.Left)
+		c.compile(x.TypeCast)
+		c.emit(CAST, c.operator(x.Operator))
+	case p.Call:
+		c.compile(x.Callee)
+		for _, arg := range x.Args {
+			c.compile(arg)
+		}
+		c.emit(CALL, len(x.Args), c.operator(x.Token))
+	case p.Lambda:
+		c.emit(CLOSURE, c.constant(compileFunction(x.Declaration)))
+	case p.Multiple:
+		for _, v := range x.Values {
+			c.compile(v)
+		}
+		c.emit(TUPLE, len(x.Values))
+	case p.Identifier:
+		c.emit(GET, x.Depth, x.Slot, c.operator(x.Name))
+	case p.Literal:
+		c.emit(CONSTANT, c.constant(x.Value))
+	case p.Type:
+		c.emit(CONSTANT, c.constant(x))
+	case p.Grouping:
+		c.compile(x.Expression)
+	case p.Case:
+		c.arms(x)
+	case p.Access, p.PositionAccess:
+		var skips []int
+		c.link(x, &skips)
+		for _, i := range skips {
+			c.Code[i].B = len(c.Code)
+		}
+	case p.AccessAssign:
+		c.compile(x.Value)
+		c.emit(SET_FIELD, c.constant(x))
+	case p.PositionAssign:
+		c.compile(x.Value)
+		c.emit(SET_INDEX, c.constant(x))
+	case p.ArrayLiteral:
+		c.compile(x.Size)
+		for _, v := range x.Values {
+			c.compile(v)
+		}
+		c.emit(ARRAY, c.constant(x), len(x.Values))
+	case p.SliceLiteral:
+		for _, v := range x.Values {
+			c.compile(v)
+		}
+		c.emit(SLICE, c.constant(x), len(x.Values))
+	case p.MapLiteral:
+		for i := range x.Keys {
+			c.compile(x.Keys[i])
+			c.compile(x.Values[i])
+		}
+		c.emit(MAP, c.constant(x), len(x.Keys))
+	default:
+		c.emit(EVAL, c.constant(node))
+	}
+}
+
+// enter opens a loop for the breaks and continues that aim at it, a loop none aims at needs nothing
+func (c *Chunk) enter(label string, breaks bool) int {
+	if !breaks {
+		return -1
+	}
+	k := c.constant(Loop{Label: label})
+	c.emit(LOOP, k)
+	return k
+}
+
+// exit closes the loop opened by enter, a break comes here and a continue goes to next
+func (c *Chunk) exit(k int, next int) {
+	if k < 0 {
+		return
+	}
+	c.Constants[k] = Loop{Label: c.Constants[k].(Loop).Label, Break: len(c.Code), Continue: next}
+	c.emit(END_LOOP)
+}
+
+// arms tries every arm of a case in a scope of its own, the subject stays on the stack until one matches
+func (c *Chunk) arms(x p.Case) {
+	var exits []int
+
+	c.compile(x.Subject)
+	for _, arm := range x.Arms {
+		c.emit(SCOPE, arm.Slots)
+		skips := []int{c.emit(MATCH, 0, c.constant(arm.Patterns))}
+		if arm.Guard != nil {
+			c.compile(arm.Guard)
+			skips = append(skips, c.emit(JUMP_IF_FALSE))
+		}
+		c.emit(POP)
+		c.compile(arm.Body)
+		c.emit(END_SCOPE)
+		exits = append(exits, c.emit(JUMP))
+
+		for _, i := range skips {
+			c.patch(i)
+		}
+		c.emit(END_SCOPE)
+	}
+	c.emit(POP)
+
+	if x.Else != nil {
+		c.compile(x.Else)
+	} else {
+		c.emit(CONSTANT, c.constant(nil))
+	}
+	for _, i := range exits {
+		c.patch(i)
+	}
+}
+
+// link compiles a step of a navigation chain like `a?.b[i].c()`, the MEMBER of a `?.` that finds
+// nil skips to the end of the whole chain, so skips gathers them to be pointed there
+func (c *Chunk) link(x p.Expr, skips *[]int) {
+	switch step := x.(type) {
+	case p.Access:
+		c.link(step.Left, skips)
+		call, isCall := step.Right.(p.Call)
+		name := c.operator(step.Name())
+		*skips = append(*skips, c.emit(MEMBER, c.constant(step), 0, name))
+		if isCall {
+			for _, arg := range call.Args {
+				c.compile(arg)
+			}
+			c.emit(CALL, len(call.Args), name)
+		}
+	case p.PositionAccess:
+		c.link(step.Expression, skips)
+		c.compile(step.Pos)
+		c.emit(INDEX, c.constant(step))
+	default:
+		c.compile(x)
+	}
+}
+
+// leave is the jump out of a loop, taken when the condition stops holding
+func leave(until bool) Op {
+	if until {
+		return JUMP_IF_TRUE
+	}
+	return JUMP_IF_FALSE
+}
+
+func (c *Chunk) binary(left p.Expr, op l.Token, right p.Expr) {
+	c.compile(left)
+	c.compile(right)
+	c.emit(BINARY, c.operator(op))
+}
+
+func (c *Chunk) branch(condition p.Expr, then p.Expr, otherwise p.Expr) {
+	c.compile(condition)
+	skip := c.emit(JUMP_IF_FALSE)
+	c.compile(then)
+	exit := c.emit(JUMP)
+	c.patch(skip)
+	if otherwise != nil {
+		c.compile(otherwise)
+	} else {
+		c.emit(CONSTANT, c.constant(nil))
+	}
+	c.patch(exit)
+}
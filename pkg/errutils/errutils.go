@@ -9,6 +9,8 @@ import (
 const (
 	LEXER                  = "lexer"
 	PARSER                 = "parser"
+	RESOLVER               = "resolver"
+	WARNING                = "warning"
 	RUNTIME                = "runtime"
 	UNTERMINATED_STATEMENT = "unterminated_statement"
 )
@@ -33,7 +35,11 @@ func (e NeonError) Error() string {
 	}
 
 	message += fmt.Sprintf("| %s\n", e.Message)
-	message += fmt.Sprintf("| [Line %d, Column %d] - %s error", e.Line, e.Column, e.ErrorType)
+	if e.ErrorType == WARNING {
+		message += fmt.Sprintf("| [Line %d, Column %d] - warning", e.Line, e.Column)
+	} else {
+		message += fmt.Sprintf("| [Line %d, Column %d] - %s error", e.Line, e.Column, e.ErrorType)
+	}
 	return message
 }
 
@@ -34,6 +34,10 @@ var keywords = map[string]TokenType{
 	"trigger": TRIGGER,
 	"trait":   TRAIT,
 	"this":    THIS,
+	"chan":    CHAN,
+
+	"break":    BREAK,
+	"continue": CONTINUE,
 
 	"put":     PUT,
 	"print":   PRINT,
@@ -68,5 +72,5 @@ func (t TokenType) IsType() bool {
 }
 
 func (t TokenType) IsValidType() bool {
-	return t == INT || t == UINT || t == FLOAT || t == BOOL || t == CHAR || t == STRING || t == UNDEFINED
+	return t.IsType() && t != ANY || t == UNDEFINED
 }
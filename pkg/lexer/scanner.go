@@ -3,6 +3,7 @@ package lexer
 import (
 	"fmt"
 	"strconv"
+	"strings"
 
 	"github.com/ToniLommez/Neon_Dream_Runner/pkg/errutils"
 )
@@ -143,11 +144,23 @@ func (s *Scanner) string() error {
 	return nil
 }
 
-func (s *Scanner) number() {
-	isFloat := false
+// number reads an integer in decimal, hex (0x), octal (0o) or binary (0b), or a decimal float with a
+// fraction, an exponent or both. Digits may be grouped with _, as in 1_000_000
+func (s *Scanner) number() error {
+	if s.source[s.start] == '0' {
+		switch s.peek() {
+		case 'x', 'X':
+			return s.based(16)
+		case 'o', 'O':
+			return s.based(8)
+		case 'b', 'B':
+			return s.based(2)
+		}
+	}
 
-	for isDigit(s.peek()) {
-		s.advance()
+	isFloat := false
+	if err := s.digits(10); err != nil {
+		return err
 	}
 
 	// Look for a fractional part.
@@ -157,18 +170,102 @@ func (s *Scanner) number() {
 		// Consume the "."
 		s.advance()
 
-		for isDigit(s.peek()) {
+		if err := s.digits(10); err != nil {
+			return err
+		}
+	}
+
+	if s.peek() == 'e' || s.peek() == 'E' {
+		isFloat = true
+		s.advance()
+
+		if s.peek() == '+' || s.peek() == '-' {
 			s.advance()
 		}
+		if !isDigit(s.peek()) {
+			return s.invalid("the exponent of a number needs digits")
+		}
+		if err := s.digits(10); err != nil {
+			return err
+		}
+	}
+
+	if s.peek() == '.' && isDigit(s.peekNext()) {
+		return s.invalid("a number has a single decimal point")
+	}
+	if isAlpha(s.peek()) {
+		return s.invalid(fmt.Sprintf("invalid digit '%c' in a decimal number", s.peek()))
 	}
 
+	text := strings.ReplaceAll(s.source[s.start:s.current], "_", "")
 	if isFloat {
-		f, _ := strconv.ParseFloat(s.source[s.start:s.current], 64)
+		f, err := strconv.ParseFloat(text, 64)
+		if err != nil {
+			return s.overflow("float")
+		}
 		s.addToken(FLOAT_LITERAL, f)
 	} else {
-		n, _ := strconv.Atoi(s.source[s.start:s.current])
-		s.addToken(NUMBER_LITERAL, n)
+		n, err := strconv.ParseInt(text, 10, 64)
+		if err != nil {
+			return s.overflow("int")
+		}
+		s.addToken(NUMBER_LITERAL, int(n))
 	}
+	return nil
+}
+
+// based reads the digits after the 0x, 0o or 0b of an integer
+func (s *Scanner) based(base int) error {
+	prefix := s.source[s.start : s.current+1]
+	s.advance()
+
+	if digitValue(s.peek()) >= base {
+		return s.invalid(fmt.Sprintf("%s needs %s digits after it", prefix, bases[base]))
+	}
+	if err := s.digits(base); err != nil {
+		return err
+	}
+	if s.peek() == '.' && isAlphaNumeric(s.peekNext()) {
+		return s.invalid(fmt.Sprintf("a %s number cannot have a fraction", bases[base]))
+	}
+
+	text := strings.ReplaceAll(s.source[s.start+2:s.current], "_", "")
+	n, err := strconv.ParseInt(text, base, 64)
+	if err != nil {
+		return s.overflow("int")
+	}
+	s.addToken(NUMBER_LITERAL, int(n))
+	return nil
+}
+
+// digits reads the digits of a number in base, an _ may only stand between two of them
+func (s *Scanner) digits(base int) error {
+	for {
+		c := s.peek()
+		switch {
+		case c == '_':
+			if digitValue(s.source[s.current-1]) >= base || digitValue(s.peekNext()) >= base {
+				return s.invalid("_ can only separate digits")
+			}
+		case digitValue(c) < base:
+		case base != 10 && isAlphaNumeric(c):
+			return s.invalid(fmt.Sprintf("invalid digit '%c' in a %s number", c, bases[base]))
+		default:
+			return nil
+		}
+		s.advance()
+	}
+}
+
+// invalid points at the character the number cannot take
+func (s *Scanner) invalid(msg string) error {
+	return errutils.Error(s.line, s.column, string(s.peek()), errutils.LEXER, msg)
+}
+
+// overflow points at a whole number too large for its type
+func (s *Scanner) overflow(typ string) error {
+	text := s.source[s.start:s.current]
+	return errutils.Error(s.line, s.column-len(text), text, errutils.LEXER, fmt.Sprintf("%s does not fit in %s", text, typ))
 }
 
 func (s *Scanner) identifier() {
@@ -228,7 +325,7 @@ func (s *Scanner) scanToken() (err error) {
 		err = s.string()
 	default:
 		if isDigit(c) {
-			s.number()
+			err = s.number()
 		} else if isAlpha(c) {
 			s.identifier()
 		} else {
@@ -100,6 +100,8 @@ const (
 	UNTIL          TokenType = "UNTIL"     // until
 	DO             TokenType = "DO"        // do
 	IN             TokenType = "IN"        // in
+	BREAK          TokenType = "BREAK"     // break
+	CONTINUE       TokenType = "CONTINUE"  // continue
 	PULSE          TokenType = "PULSE"     // pulse
 	BEFORE         TokenType = "BEFORE"    // before
 	INSIDE         TokenType = "INSIDE"    // inside
@@ -120,6 +122,7 @@ const (
 	TRIGGER        TokenType = "TRIGGER"   // trigger
 	TRAIT          TokenType = "TRAIT"     // trait
 	THIS           TokenType = "THIS"      // this
+	CHAN           TokenType = "CHAN"      // chan
 	PUT            TokenType = "PUT"       // put
 	PRINT          TokenType = "PRINT"     // print
 	PRINTF         TokenType = "PRINTF"    // printf
@@ -13,3 +13,19 @@ func isDigit(c byte) bool {
 func isAlphaNumeric(c byte) bool {
 	return isAlpha(c) || isDigit(c)
 }
+
+// bases names the bases a number can be written in
+var bases = map[int]string{2: "binary", 8: "octal", 10: "decimal", 16: "hex"}
+
+// digitValue is the value of c as a digit of any base up to 36, or 36 when it is not a digit
+func digitValue(c byte) int {
+	switch {
+	case isDigit(c):
+		return int(c - '0')
+	case c >= 'a' && c <= 'z':
+		return int(c-'a') + 10
+	case c >= 'A' && c <= 'Z':
+		return int(c-'A') + 10
+	}
+	return 36
+}
@@ -0,0 +1,34 @@
+package parser
+
+// Array has a fixed size and every element has its Type, or any when Type is UNDEFINED.
+// Copies of an array share its elements, so `a[i] = v` is seen by all of them.
+// ReadOnly is set on an array read from an immutable variable, nothing changes through it
+type Array struct {
+	Type     int
+	Values   []any
+	ReadOnly bool
+}
+
+// holds tells if a value may be stored in the array
+func (a Array) holds(v any) bool {
+	return a.Type == UNDEFINED || getType(v) == a.Type
+}
+
+// at reads the element i, which should be in the array
+func (a Array) at(i int) any {
+	defer viewing()()
+	return a.Values[i]
+}
+
+// put writes the element i, which should be in the array
+func (a Array) put(i int, v any) {
+	release := changing()
+	a.Values[i] = v
+	release()
+	touched(a)
+}
+
+func (a Array) String() string {
+	values, _ := snapshot(a)
+	return list(values)
+}
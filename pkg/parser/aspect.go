@@ -0,0 +1,108 @@
+package parser
+
+import (
+	"fmt"
+	"strings"
+	"sync"
+
+	l "github.com/ToniLommez/Neon_Dream_Runner/pkg/lexer"
+)
+
+// Advice is the code a `pulse` injects into a function, Target is the name in the declaration
+// of the function, Closure the scope where the pulse ran and Pulse the Number of the pulse
+type Advice struct {
+	When    l.TokenType
+	Target  l.Token
+	Label   string
+	Params  int
+	Body    Expr
+	Closure *Scope
+	Pulse   int
+}
+
+func (a Advice) String() string {
+	str := fmt.Sprintf("pulse %s %s", strings.ToLower(string(a.When)), a.Target.Lexeme)
+	if a.When == l.INSIDE {
+		str += " @" + a.Label
+	}
+	return fmt.Sprintf("%s (fn at line %d)", str, a.Target.Line)
+}
+
+// targets tells if the advice belongs to the function declared with that name
+func (a Advice) targets(fn l.Token) bool {
+	return a.Target.Lexeme == fn.Lexeme && a.Target.Line == fn.Line && a.Target.Column == fn.Column
+}
+
+// Aspects keeps every advice in the order the pulses first ran
+type Aspects struct {
+	advices []Advice
+	mu      sync.Mutex
+}
+
+// Add registers an advice, a pulse that runs again replaces the one it gave before and keeps its place.
+// The list is copied to replace it, since calls may be going through the one List gave them
+func (as *Aspects) Add(a Advice) {
+	as.mu.Lock()
+	defer as.mu.Unlock()
+	for i, old := range as.advices {
+		if a.Pulse != 0 && old.Pulse == a.Pulse {
+			as.advices = append([]Advice(nil), as.advices...)
+			as.advices[i] = a
+			return
+		}
+	}
+	as.advices = append(as.advices, a)
+}
+
+func (as *Aspects) List() []Advice {
+	as.mu.Lock()
+	defer as.mu.Unlock()
+	return as.advices
+}
+
+// For lists the advices of a function for a moment of its call. Before and inside run in the order
+// they were added, after runs in the opposite one, so the first pulse wraps all the others
+func (as *Aspects) For(when l.TokenType, fn l.Token, label string) []Advice {
+	found := make([]Advice, 0)
+	for _, a := range as.List() {
+		if a.When == when && a.Label == label && a.targets(fn) {
+			found = append(found, a)
+		}
+	}
+
+	if when == l.AFTER {
+		for i, j := 0, len(found)-1; i < j; i, j = i+1, j-1 {
+			found[i], found[j] = found[j], found[i]
+		}
+	}
+	return found
+}
+
+func (s *Scope) aspects() *Aspects {
+	root := s.root()
+	if root.Aspects == nil {
+		root.Aspects = &Aspects{}
+	}
+	return root.Aspects
+}
+
+// Advise runs the advices of the function fn for a moment of its call, frame is the scope of the call.
+// Each one runs next to the parameters, so it sees and changes the same values the function does
+func (s *Scope) Advise(when l.TokenType, fn l.Token, label string, frame *Scope) error {
+	for _, a := range s.aspects().For(when, fn, label) {
+		view := &Scope{Values: frame.Values[:a.Params:a.Params], Parent: a.Closure}
+		if _, err := view.evaluate(a.Body); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (s *Scope) PulseEval(p PulseStmt) (any, error) {
+	s.aspects().Add(Advice{When: p.When.Type, Target: p.Function, Label: p.Label.Lexeme, Params: p.Params, Body: p.Body, Closure: s, Pulse: p.Number})
+	return nil, nil
+}
+
+func (s *Scope) JoinPointEval(j JoinPoint) (any, error) {
+	return nil, s.Advise(l.INSIDE, j.Function, j.Label.Lexeme, s.ancestor(j.Depth))
+}
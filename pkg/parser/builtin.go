@@ -0,0 +1,111 @@
+package parser
+
+import (
+	"fmt"
+	"unicode/utf8"
+
+	e "github.com/ToniLommez/Neon_Dream_Runner/pkg/errutils"
+	l "github.com/ToniLommez/Neon_Dream_Runner/pkg/lexer"
+)
+
+// Native is a function written in Go, it is called like any other function. Changes tells it changes
+// the collection of its first argument in place, so the Resolver only lets it take mutable variables
+type Native struct {
+	Name    string
+	Arity   int
+	Changes bool
+	Fn      func(at l.Token, args []any) (any, error)
+}
+
+func (n Native) Call(args []any, at l.Token) (any, error) {
+	if len(args) != n.Arity {
+		return nil, e.Error(at.Line, at.Column, at.Lexeme, e.RUNTIME, fmt.Sprintf("%s expects %d arguments, found %d", n.Name, n.Arity, len(args)))
+	}
+
+	// builtins never rebind a variable, what was lent with x! is read as it is
+	for i, arg := range args {
+		if ref, ok := arg.(Reference); ok {
+			v, err := ref.value()
+			if err != nil {
+				return nil, err
+			}
+			args[i] = v
+		}
+	}
+	return n.Fn(at, args)
+}
+
+func (n Native) String() string {
+	return fmt.Sprintf("<native %s>", n.Name)
+}
+
+// builtins live in the first slots of the global scope, the Resolver declares them in the same order
+var builtins = []Native{
+	{Name: "len", Arity: 1, Fn: length},
+	{Name: "push", Arity: 2, Changes: true, Fn: push},
+	{Name: "delete", Arity: 2, Changes: true, Fn: remove},
+	{Name: "close", Arity: 1, Fn: closeChannel},
+}
+
+// length counts the elements of a collection, a string has one for each char
+func length(at l.Token, args []any) (any, error) {
+	switch v := args[0].(type) {
+	case string:
+		return utf8.RuneCountInString(v), nil
+	case Tuple:
+		return len(v), nil
+	case Array:
+		return len(v.Values), nil
+	case Slice:
+		return v.Len(), nil
+	case Map:
+		return v.Len(), nil
+	case Interval:
+		return v.Len(), nil
+	default:
+		return nil, e.Error(at.Line, at.Column, at.Lexeme, e.RUNTIME, fmt.Sprintf("len expects a collection, found: %v", args[0]))
+	}
+}
+
+// push appends to a slice in place and returns it
+func push(at l.Token, args []any) (any, error) {
+	s, ok := args[0].(Slice)
+	if !ok {
+		return nil, e.Error(at.Line, at.Column, at.Lexeme, e.RUNTIME, fmt.Sprintf("push expects a slice, found: %v", args[0]))
+	}
+	if s.ReadOnly {
+		return nil, e.Error(at.Line, at.Column, at.Lexeme, e.RUNTIME, "push cannot change a slice held by an immutable variable")
+	}
+	v, err := fit(at, s.Type, args[1])
+	if err != nil {
+		return nil, err
+	}
+	args[1] = v
+	if !s.holds(args[1]) {
+		return nil, e.Error(at.Line, at.Column, at.Lexeme, e.RUNTIME, fmt.Sprintf("slice of %s cannot hold %s: %v", typeToString(s.Type), typeToString(getType(args[1])), args[1]))
+	}
+
+	s.push(args[1])
+	return s, nil
+}
+
+// remove deletes a key from a map in place and tells if it was there
+func remove(at l.Token, args []any) (any, error) {
+	m, ok := args[0].(Map)
+	if !ok {
+		return nil, e.Error(at.Line, at.Column, at.Lexeme, e.RUNTIME, fmt.Sprintf("delete expects a map, found: %v", args[0]))
+	}
+	if m.ReadOnly {
+		return nil, e.Error(at.Line, at.Column, at.Lexeme, e.RUNTIME, "delete cannot change a map held by an immutable variable")
+	}
+	return m.Delete(args[1]), nil
+}
+
+// closeChannel tells the receivers of a channel that no more values will come
+func closeChannel(at l.Token, args []any) (any, error) {
+	c, ok := args[0].(Channel)
+	if !ok {
+		return nil, e.Error(at.Line, at.Column, at.Lexeme, e.RUNTIME, fmt.Sprintf("close expects a channel, found: %v", args[0]))
+	}
+	return nil, c.Close(at)
+}
@@ -0,0 +1,186 @@
+package parser
+
+import (
+	"fmt"
+
+	e "github.com/ToniLommez/Neon_Dream_Runner/pkg/errutils"
+	l "github.com/ToniLommez/Neon_Dream_Runner/pkg/lexer"
+)
+
+// The directions of a channel, kept in the Key of its variables as the element type is kept in Elem
+const (
+	bidirectional = iota // chan <!> T, values go in and come out
+	inbound              // chan <! T, values only go in
+	outbound             // chan !> T, values only come out
+)
+
+// Channel is the value of `chan T`, Type is the type of its values and Direction what may be done
+// with it. Copies share the same queue, a restricted copy is what a typed variable or parameter keeps
+type Channel struct {
+	Type      int
+	Direction int
+	queue     *queue
+}
+
+// queue holds what was sent and not received yet, the first size of them are the buffer and the others
+// wait for a receive. It is guarded by the mutex of the Tasks, so a send or a receive that waits is a waiter
+type queue struct {
+	tasks   *Tasks
+	size    int
+	pending []*parcel
+	closed  bool
+}
+
+// parcel is a value sent to a channel, dropped when the channel closed before it reached the buffer
+type parcel struct {
+	value   any
+	dropped bool
+}
+
+// NewChannel makes a channel that holds up to size values before a send waits, 0 waits for every receive
+func NewChannel(at l.Token, tasks *Tasks, tp int, size any) (Channel, error) {
+	n, ok := size.(int)
+	if !ok || n < 0 {
+		return Channel{}, e.Error(at.Line, at.Column, at.Lexeme, e.RUNTIME, fmt.Sprintf("channel size should be a positive int, found: %v", size))
+	}
+	return Channel{Type: tp, queue: &queue{tasks: tasks, size: n}}, nil
+}
+
+// buffered tells if the parcel was taken or is in the buffer, where the send does not wait anymore
+func (q *queue) buffered(p *parcel) bool {
+	for i, x := range q.pending {
+		if x == p {
+			return i < q.size
+		}
+	}
+	return true
+}
+
+func (q *queue) remove(p *parcel) {
+	for i, x := range q.pending {
+		if x == p {
+			q.pending = append(q.pending[:i], q.pending[i+1:]...)
+			return
+		}
+	}
+}
+
+// narrow restricts a channel to the direction declared for where it goes
+func (c Channel) narrow(direction int) Channel {
+	if c.Direction == bidirectional && (direction == inbound || direction == outbound) {
+		c.Direction = direction
+	}
+	return c
+}
+
+func (c Channel) holds(v any) bool {
+	return c.Type == UNDEFINED || getType(v) == c.Type
+}
+
+// Send waits until the value is taken, or fits in the buffer
+func (c Channel) Send(at l.Token, v any) error {
+	if c.Direction == outbound {
+		return e.Error(at.Line, at.Column, at.Lexeme, e.RUNTIME, "cannot send to a channel that only gives values out")
+	}
+	v, err := fit(at, c.Type, v)
+	if err != nil {
+		return err
+	}
+	if !c.holds(v) {
+		return e.Error(at.Line, at.Column, at.Lexeme, e.RUNTIME, fmt.Sprintf("channel of %s cannot take %s", typeToString(c.Type), describeValue(v)))
+	}
+
+	q := c.queue
+	q.tasks.mu.Lock()
+	defer q.tasks.mu.Unlock()
+	if q.closed {
+		return e.Error(at.Line, at.Column, at.Lexeme, e.RUNTIME, "cannot send to a closed channel")
+	}
+
+	p := &parcel{value: v}
+	q.pending = append(q.pending, p)
+	q.tasks.changed.Broadcast()
+	if !q.tasks.wait(func() bool { return q.buffered(p) }) {
+		q.remove(p)
+		return e.Error(at.Line, at.Column, at.Lexeme, e.RUNTIME, "deadlock, the send waits for a receive that nothing else will make")
+	}
+
+	// a send that was waiting when the channel closed fails like one that came after
+	if p.dropped {
+		return e.Error(at.Line, at.Column, at.Lexeme, e.RUNTIME, "cannot send to a closed channel")
+	}
+	return nil
+}
+
+// Receive waits for a value, a closed channel gives nil once it is empty
+func (c Channel) Receive(at l.Token) (any, error) {
+	if c.Direction == inbound {
+		return nil, e.Error(at.Line, at.Column, at.Lexeme, e.RUNTIME, "cannot receive from a channel that only takes values in")
+	}
+	v, _, err := c.receive(at)
+	return v, err
+}
+
+// receive is Receive telling if the value came from a send, false once the channel is closed and empty
+func (c Channel) receive(at l.Token) (any, bool, error) {
+	q := c.queue
+	q.tasks.mu.Lock()
+	defer q.tasks.mu.Unlock()
+	if !q.tasks.wait(func() bool { return len(q.pending) > 0 || q.closed }) {
+		return nil, false, e.Error(at.Line, at.Column, at.Lexeme, e.RUNTIME, "deadlock, the receive waits for a send that nothing else will make")
+	}
+	if len(q.pending) == 0 {
+		return nil, false, nil
+	}
+
+	p := q.pending[0]
+	q.pending = q.pending[1:]
+	q.tasks.changed.Broadcast()
+	return p.value, true, nil
+}
+
+// Close tells the receivers no more values will come, the sends still waiting for one fail
+func (c Channel) Close(at l.Token) error {
+	q := c.queue
+	q.tasks.mu.Lock()
+	defer q.tasks.mu.Unlock()
+	if q.closed {
+		return e.Error(at.Line, at.Column, at.Lexeme, e.RUNTIME, "channel is already closed")
+	}
+	q.closed = true
+	if len(q.pending) > q.size {
+		for _, p := range q.pending[q.size:] {
+			p.dropped = true
+		}
+		q.pending = q.pending[:q.size]
+	}
+	q.tasks.changed.Broadcast()
+	return nil
+}
+
+func (c Channel) String() string {
+	return fmt.Sprintf("<%s>", describe(CHANNEL, c.Type, c.Direction, ""))
+}
+
+func (s *Scope) SendEval(x Send) (any, error) {
+	target, v, err := s.operands(x.Channel, x.Value)
+	if err != nil {
+		return nil, err
+	}
+	c, ok := target.(Channel)
+	if !ok {
+		return nil, e.Error(x.Arrow.Line, x.Arrow.Column, x.Arrow.Lexeme, e.RUNTIME, fmt.Sprintf("expect a channel before <!, found: %v", target))
+	}
+	return nil, c.Send(x.Arrow, v)
+}
+
+func (s *Scope) ChannelLiteralEval(c ChannelLiteral) (any, error) {
+	var size any = 0
+	if c.Size != nil {
+		var err error
+		if size, err = s.evaluate(c.Size); err != nil {
+			return nil, err
+		}
+	}
+	return NewChannel(c.Keyword, s.tasks(), c.Type, size)
+}
@@ -0,0 +1,123 @@
+package parser
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/ToniLommez/Neon_Dream_Runner/pkg/lexer"
+)
+
+// run scans, parses, resolves and interprets source as a script, and gives the value of its last statement
+func run(t *testing.T, source string) any {
+	t.Helper()
+
+	s := lexer.NewScanner(source)
+	tokens, err := s.ScanTokens(true)
+	if err != nil {
+		t.Fatalf("scan: %v", err)
+	}
+
+	p := NewParser(tokens)
+	statements, err := p.Parse()
+	if err != nil {
+		t.Fatalf("parse: %v", err)
+	}
+
+	var neon Program
+	neon.Init(false)
+	if statements, err = neon.Resolver.Resolve(statements); err != nil {
+		t.Fatalf("resolve: %v", err)
+	}
+
+	neon.Main.Statements = statements
+	v, err := neon.Main.Interpret()
+	if err != nil {
+		t.Fatalf("interpret: %v", err)
+	}
+	return v
+}
+
+func TestCounterKeepsItsState(t *testing.T) {
+	v := run(t, `
+fn make_counter {
+    let! count = 0
+    fn next => int {
+        count += 1
+        => count
+    }
+    => next
+}
+
+let next = make_counter()
+next()
+next()
+next()
+`)
+	if v != 3 {
+		t.Errorf("the third call of the counter gave %v, want 3", v)
+	}
+}
+
+func TestAdderFactory(t *testing.T) {
+	v := run(t, `
+fn make_adder(n: int) {
+    fn add(x: int) => int {
+        => x + n
+    }
+    => add
+}
+
+let add3 = make_adder 3
+let add10 = make_adder 10
+(add3 4, add10 4, add3(add10 1))
+`)
+	if want := (Tuple{7, 14, 14}); !reflect.DeepEqual(v, want) {
+		t.Errorf("the adders gave %v, want %v", v, want)
+	}
+}
+
+func TestClosuresOfOneCallShareTheirVariables(t *testing.T) {
+	v := run(t, `
+fn make_account {
+    let! balance = 0
+    fn deposit(n: int) {
+        balance += n
+    }
+    fn read => int {
+        => balance
+    }
+    => deposit, read
+}
+
+let deposit, read = make_account()
+deposit 10
+deposit 5
+read()
+`)
+	if v != 15 {
+		t.Errorf("read saw a balance of %v after the deposits, want 15", v)
+	}
+}
+
+func TestFactoryCallsHaveTheirOwnState(t *testing.T) {
+	v := run(t, `
+fn make_counter {
+    let! count = 0
+    fn next => int {
+        count += 1
+        => count
+    }
+    => next
+}
+
+let a = make_counter()
+let b = make_counter()
+a()
+a()
+a()
+(a(), b())
+`)
+	if want := (Tuple{4, 1}); !reflect.DeepEqual(v, want) {
+		t.Errorf("the counters gave %v, want %v", v, want)
+	}
+}
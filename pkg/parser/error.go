@@ -0,0 +1,288 @@
+package parser
+
+import (
+	"fmt"
+
+	e "github.com/ToniLommez/Neon_Dream_Runner/pkg/errutils"
+	l "github.com/ToniLommez/Neon_Dream_Runner/pkg/lexer"
+)
+
+// errName is the type of errors, the name of their literal and of the error inside a handler
+const errName = "err"
+
+// Err is an error of the program, as opposed to the errors of the interpreter. Kind is the name
+// that tells errors apart, an err of kind ok is no error at all
+type Err struct {
+	Kind  string
+	Msg   string
+	Stack []Trace
+}
+
+// Trace is a step of the stack of an error, Function is empty for the script itself
+type Trace struct {
+	Function string
+	Line     int
+}
+
+func (t Trace) String() string {
+	if t.Function == "" {
+		return fmt.Sprintf("at line %d", t.Line)
+	}
+	return fmt.Sprintf("at %s, line %d", t.Function, t.Line)
+}
+
+func (x Err) String() string {
+	return fmt.Sprintf("err{type: %s, msg: \"%s\"}", x.Kind, x.Msg)
+}
+
+func (x Err) failed() bool {
+	return x.Kind != "ok"
+}
+
+// member reads `err.type`, `err.msg`, `err.stack` and `err.line`, any other name is the
+// kind it names, so `case err of err.fatal => ...` compares kinds
+func (x Err) member(name l.Token) any {
+	switch name.Lexeme {
+	case "type":
+		return x.Kind
+	case "msg":
+		return x.Msg
+	case "stack":
+		stack := make([]any, len(x.Stack))
+		for i, t := range x.Stack {
+			stack[i] = t.String()
+		}
+		return NewSlice(STRING, stack)
+	case "line":
+		if len(x.Stack) == 0 {
+			return 0
+		}
+		return x.Stack[0].Line
+	default:
+		return Err{Kind: name.Lexeme}
+	}
+}
+
+// Frame is what a call leaves in the scope of the function body, Caller is the scope that called it
+type Frame struct {
+	Function l.Token
+	At       l.Token
+	Caller   *Scope
+}
+
+// trace walks the calls that led to s, from the innermost function out to the script
+func (s *Scope) trace(at l.Token) []Trace {
+	stack := make([]Trace, 0)
+	line := at.Line
+	for scope := s; scope != nil; {
+		if scope.Frame == nil {
+			scope = scope.Parent
+			continue
+		}
+		stack = append(stack, Trace{Function: scope.Frame.Function.Lexeme, Line: line})
+		line = scope.Frame.At.Line
+		scope = scope.Frame.Caller
+	}
+	return append(stack, Trace{Line: line})
+}
+
+// raiseSignal is an error raised by `error`, it travels up as an error until a `?` handles it or
+// a function with an err slot returns it. Returning is set by a bare `?`, which returns the error
+// from the function it is in even without an err slot
+type raiseSignal struct {
+	Err       Err
+	At        l.Token
+	Returning bool
+}
+
+func (r raiseSignal) Error() string {
+	return Uncaught(r).Error()
+}
+
+// Uncaught turns an error raised with `error` that nothing handled into the error that reports it
+func Uncaught(err error) error {
+	r, ok := err.(raiseSignal)
+	if !ok {
+		return err
+	}
+
+	msg := "uncaught error " + r.Err.Kind
+	if r.Err.Msg != "" {
+		msg += ": " + r.Err.Msg
+	}
+	for _, t := range r.Err.Stack {
+		msg += "\n|     " + t.String()
+	}
+	return e.Error(r.At.Line, r.At.Column, r.At.Lexeme, e.RUNTIME, msg)
+}
+
+// failure is the error a value carries, an err itself or one in the last place of a tuple
+func failure(v any) (Err, bool) {
+	switch x := v.(type) {
+	case Err:
+		return x, x.failed()
+	case Tuple:
+		if len(x) > 0 {
+			if err, ok := x[len(x)-1].(Err); ok {
+				return err, err.failed()
+			}
+		}
+	}
+	return Err{}, false
+}
+
+// success drops the err slot, the last one, of the values of a call that did not fail
+func success(v any) any {
+	t, ok := v.(Tuple)
+	if !ok || len(t) < 2 {
+		return v
+	}
+	if _, isErr := t[len(t)-1].(Err); !isErr && t[len(t)-1] != nil {
+		return v
+	}
+	if len(t) == 2 {
+		return t[0]
+	}
+	return t[:len(t)-1]
+}
+
+// handle runs what comes after a failure, with the error in `err`
+func (s *Scope) handle(failed Err, x Expr) (any, error) {
+	scope := &Scope{Values: make([]Variable, 0, 1), Parent: s}
+	let := LetStmt{Name: l.Token{Type: l.IDENTIFIER, Lexeme: errName}, Type: ERR, Initializer: Literal{failed}}
+	if _, err := scope.Define(let, failed); err != nil {
+		return nil, err
+	}
+	return scope.evaluate(x)
+}
+
+// message evaluates the message of an error, which is a string
+func (s *Scope) message(at l.Token, x Expr) (string, error) {
+	if x == nil {
+		return "", nil
+	}
+	v, err := s.evaluate(x)
+	if err != nil {
+		return "", err
+	}
+	msg, ok := v.(string)
+	if !ok {
+		return "", e.Error(at.Line, at.Column, at.Lexeme, e.RUNTIME, fmt.Sprintf("error message should be a string, found: %v", v))
+	}
+	return msg, nil
+}
+
+// An err keeps the stack of where it was made, raising one made elsewhere does not change it
+func (s *Scope) ErrorEval(x ErrorStmt) (any, error) {
+	raised := Err{Kind: "error"}
+	if x.Value != nil {
+		v, err := s.evaluate(x.Value)
+		if err != nil {
+			return nil, err
+		}
+		made, ok := v.(Err)
+		if !ok || !made.failed() {
+			return nil, e.Error(x.Keyword.Line, x.Keyword.Column, x.Keyword.Lexeme, e.RUNTIME, fmt.Sprintf("error expects an err that is not ok, found: %v", v))
+		}
+		raised = made
+	} else {
+		if x.Kind.Lexeme != "" {
+			raised.Kind = x.Kind.Lexeme
+		}
+		msg, err := s.message(x.Keyword, x.Message)
+		if err != nil {
+			return nil, err
+		}
+		raised.Msg = msg
+	}
+	if len(raised.Stack) == 0 {
+		raised.Stack = s.trace(x.Keyword)
+	}
+
+	if x.Results != nil {
+		v, err := s.handle(raised, x.Results)
+		if err != nil {
+			return nil, err
+		}
+		return nil, returnSignal{Keyword: x.Keyword, Value: v}
+	}
+	return nil, raiseSignal{Err: raised, At: x.Keyword}
+}
+
+func (s *Scope) ErrLiteralEval(x ErrLiteral) (any, error) {
+	msg, err := s.message(x.Keyword, x.Message)
+	if err != nil {
+		return nil, err
+	}
+
+	made := Err{Kind: "error", Msg: msg}
+	if x.Kind.Lexeme != "" {
+		made.Kind = x.Kind.Lexeme
+	}
+	if made.failed() {
+		made.Stack = s.trace(x.Keyword)
+	}
+	return made, nil
+}
+
+// A check gives the value of what did not fail, without its err slot. What failed returns from the
+// function the zero of its results, the results after `? =>`, or gives the value of the `? { }` handler
+func (s *Scope) CheckEval(c Check) (any, error) {
+	v, err := s.evaluate(c.Left)
+
+	var failed Err
+	at := c.Mark
+	if r, ok := err.(raiseSignal); ok {
+		failed, at = r.Err, r.At
+	} else if err != nil {
+		return nil, err
+	} else if x, ok := failure(v); ok {
+		failed = x
+	} else {
+		return success(v), nil
+	}
+
+	switch {
+	case c.HaveReturn:
+		res, err := s.handle(failed, c.Right)
+		if err != nil {
+			return nil, err
+		}
+		return nil, returnSignal{Keyword: c.Mark, Value: res}
+	case c.Handler != nil:
+		return s.handle(failed, c.Handler)
+	default:
+		return nil, raiseSignal{Err: failed, At: at, Returning: true}
+	}
+}
+
+// errSlot is the position of the err among the results of a function, -1 when it has none
+func (f Function) errSlot() int {
+	for i := len(f.Declaration.Returns) - 1; i >= 0; i-- {
+		if f.Declaration.Returns[i] == ERR {
+			return i
+		}
+	}
+	return -1
+}
+
+// failed is what a function returns for an error: the zero of every result and the error in its slot
+func (f Function) failed(x Err) any {
+	returns := f.Declaration.Returns
+	values := make(Tuple, len(returns))
+	for i, t := range returns {
+		values[i] = zero(t)
+	}
+	if slot := f.errSlot(); slot >= 0 {
+		values[slot] = x
+	}
+
+	switch len(values) {
+	case 0:
+		return nil
+	case 1:
+		return values[0]
+	default:
+		return values
+	}
+}
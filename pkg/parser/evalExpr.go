@@ -3,6 +3,7 @@ package parser
 import (
 	"fmt"
 	"math"
+	"strings"
 
 	e "github.com/ToniLommez/Neon_Dream_Runner/pkg/errutils"
 	"github.com/ToniLommez/Neon_Dream_Runner/pkg/lexer"
@@ -14,6 +15,10 @@ func typePrecedence(l any, r any, acceptUint bool) (any, any, int) {
 	lType := getType(l)
 	rType := getType(r)
 
+	if isSized(lType) || isSized(rType) {
+		return promote(l, r, acceptUint)
+	}
+
 	switch lType {
 	case BOOL:
 		switch rType {
@@ -93,13 +98,27 @@ func typePrecedence(l any, r any, acceptUint bool) (any, any, int) {
 		default:
 			return l, r, UNKNOWN
 		}
+	case ERR:
+		switch rType {
+		case ERR:
+			return l, r, ERR
+		default:
+			return l, r, UNKNOWN
+		}
+	case POINTER:
+		switch rType {
+		case POINTER:
+			return l, r, POINTER
+		default:
+			return l, r, UNKNOWN
+		}
 	default:
 		return l, r, UNKNOWN
 	}
 }
 
 func Truthy(value any) (r bool, err error) {
-	switch v := value.(type) {
+	switch v := widen(value).(type) {
 	case nil:
 		r = false
 	case bool:
@@ -114,6 +133,12 @@ func Truthy(value any) (r bool, err error) {
 		r = v != 0.0
 	case string:
 		r = v != ""
+	case Function:
+		r = true
+	case Err:
+		r = v.failed()
+	case Address:
+		r = true
 	default:
 		r = false
 		err = e.Error(-1, -1, "", e.RUNTIME, "truthy pattern matching not implemented, returning false")
@@ -130,64 +155,66 @@ func (s *Scope) SequenceEval(x Sequence) (any, error) {
 	return s.evaluate(x.Right)
 }
 
-func (s *Scope) AssignEval(a Assign) (res any, err error) {
+func (s *Scope) AssignEval(a Assign) (any, error) {
 	v, err := s.evaluate(a.Value)
 	if err != nil {
-		return
+		return nil, err
 	}
 
-	_, tv, d, err := s.Get(a.Target)
-	if d {
-		if err != nil {
-			return
-		}
-
-		op := func(t lexer.Token, o lexer.TokenType) lexer.Token {
-			t.Type = o
-			return t
-		}
-
-		switch a.Operator.Type {
-		case lexer.ADD_ASSIGN:
-			v, err = s.TermEval(Term{Left: Literal{tv}, Operator: op(a.Operator, lexer.PLUS), Right: Literal{v}})
-		case lexer.SUB_ASSIGN:
-			v, err = s.TermEval(Term{Left: Literal{tv}, Operator: op(a.Operator, lexer.MINUS), Right: Literal{v}})
-		case lexer.MUL_ASSIGN:
-			v, err = s.FactorEval(Factor{Left: Literal{tv}, Operator: op(a.Operator, lexer.STAR), Right: Literal{v}})
-		case lexer.DIV_ASSIGN:
-			v, err = s.FactorEval(Factor{Left: Literal{tv}, Operator: op(a.Operator, lexer.SLASH), Right: Literal{v}})
-		case lexer.MOD_ASSIGN:
-			v, err = s.FactorEval(Factor{Left: Literal{tv}, Operator: op(a.Operator, lexer.MOD), Right: Literal{v}})
-		case lexer.POW_ASSIGN:
-			v, err = s.PowerEval(Power{Left: Literal{tv}, Operator: op(a.Operator, lexer.POW), Right: Literal{v}})
-		case lexer.BITSHIFT_LEFT_ASSIGN:
-			v, err = s.BitshiftEval(Bitshift{Left: Literal{tv}, Operator: op(a.Operator, lexer.SHIFT_LEFT), Right: Literal{v}})
-		case lexer.BITSHIFT_RIGHT_ASSIGN:
-			v, err = s.BitshiftEval(Bitshift{Left: Literal{tv}, Operator: op(a.Operator, lexer.SHIFT_RIGHT), Right: Literal{v}})
-		case lexer.ROUNDSHIFT_LEFT_ASSIGN:
-			v, err = s.BitshiftEval(Bitshift{Left: Literal{tv}, Operator: op(a.Operator, lexer.ROUNDSHIFT_LEFT), Right: Literal{v}})
-		case lexer.ROUNDSHIFT_RIGHT_ASSIGN:
-			v, err = s.BitshiftEval(Bitshift{Left: Literal{tv}, Operator: op(a.Operator, lexer.ROUNDSHIFT_RIGHT), Right: Literal{v}})
-		case lexer.AND_ASSIGN:
-			v, err = s.BitwiseEval(Bitwise{Left: Literal{tv}, Operator: op(a.Operator, lexer.AND_BITWISE), Right: Literal{v}})
-		case lexer.OR_ASSIGN:
-			v, err = s.BitwiseEval(Bitwise{Left: Literal{tv}, Operator: op(a.Operator, lexer.OR_BITWISE), Right: Literal{v}})
-		case lexer.XOR_ASSIGN:
-			v, err = s.BitwiseEval(Bitwise{Left: Literal{tv}, Operator: op(a.Operator, lexer.XOR_BITWISE), Right: Literal{v}})
-		case lexer.NAND_ASSIGN:
-			v, err = s.BitwiseEval(Bitwise{Left: Literal{tv}, Operator: op(a.Operator, lexer.NAND_BITWISE), Right: Literal{v}})
-		case lexer.NOR_ASSIGN:
-			v, err = s.BitwiseEval(Bitwise{Left: Literal{tv}, Operator: op(a.Operator, lexer.NOR_BITWISE), Right: Literal{v}})
-		case lexer.XNOR_ASSIGN:
-			v, err = s.BitwiseEval(Bitwise{Left: Literal{tv}, Operator: op(a.Operator, lexer.XNOR_BITWISE), Right: Literal{v}})
-		}
+	return s.AssignValue(a, v)
+}
 
-		if err != nil {
-			return nil, err
-		}
+// AssignValue stores an already evaluated value, combining it with the old one on compound assignments
+func (s *Scope) AssignValue(a Assign, v any) (any, error) {
+	return s.Update(a.Target, a.Depth, a.Slot, a.Operator, v)
+}
+
+// compound applies the operator of `+=`, `-=` and the like to the old and the new value, `=` keeps the new one
+func compound(operator lexer.Token, tv any, v any) (any, error) {
+	var err error
+
+	op := func(o lexer.TokenType) lexer.Token {
+		t := operator
+		t.Type = o
+		return t
+	}
+
+	switch operator.Type {
+	case lexer.ADD_ASSIGN:
+		v, err = term(op(lexer.PLUS), tv, v)
+	case lexer.SUB_ASSIGN:
+		v, err = term(op(lexer.MINUS), tv, v)
+	case lexer.MUL_ASSIGN:
+		v, err = factor(op(lexer.STAR), tv, v)
+	case lexer.DIV_ASSIGN:
+		v, err = factor(op(lexer.SLASH), tv, v)
+	case lexer.MOD_ASSIGN:
+		v, err = factor(op(lexer.MOD), tv, v)
+	case lexer.POW_ASSIGN:
+		v, err = power(op(lexer.POW), tv, v)
+	case lexer.BITSHIFT_LEFT_ASSIGN:
+		v, err = bitshift(op(lexer.SHIFT_LEFT), tv, v)
+	case lexer.BITSHIFT_RIGHT_ASSIGN:
+		v, err = bitshift(op(lexer.SHIFT_RIGHT), tv, v)
+	case lexer.ROUNDSHIFT_LEFT_ASSIGN:
+		v, err = bitshift(op(lexer.ROUNDSHIFT_LEFT), tv, v)
+	case lexer.ROUNDSHIFT_RIGHT_ASSIGN:
+		v, err = bitshift(op(lexer.ROUNDSHIFT_RIGHT), tv, v)
+	case lexer.AND_ASSIGN:
+		v, err = bitwise(op(lexer.AND_BITWISE), tv, v)
+	case lexer.OR_ASSIGN:
+		v, err = bitwise(op(lexer.OR_BITWISE), tv, v)
+	case lexer.XOR_ASSIGN:
+		v, err = bitwise(op(lexer.XOR_BITWISE), tv, v)
+	case lexer.NAND_ASSIGN:
+		v, err = bitwise(op(lexer.NAND_BITWISE), tv, v)
+	case lexer.NOR_ASSIGN:
+		v, err = bitwise(op(lexer.NOR_BITWISE), tv, v)
+	case lexer.XNOR_ASSIGN:
+		v, err = bitwise(op(lexer.XNOR_BITWISE), tv, v)
 	}
 
-	return s.Set(a.Target, v)
+	return v, err
 }
 
 func (s *Scope) TernaryEval(t Ternary) (any, error) {
@@ -237,23 +264,61 @@ func (s *Scope) LogicEval(x Logic) (any, error) {
 	return Truthy(tmp)
 }
 
-func (s *Scope) EqualityEval(eq Equality) (res any, err error) {
-	l, err := s.evaluate(eq.Left)
+func (s *Scope) operands(left Expr, right Expr) (any, any, error) {
+	l, err := s.evaluate(left)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	r, err := s.evaluate(right)
 	if err != nil {
-		return
+		return nil, nil, err
 	}
 
-	r, err := s.evaluate(eq.Right)
+	return l, r, nil
+}
+
+// BinaryOp applies any infix operator to values already evaluated
+func BinaryOp(op lexer.Token, l any, r any) (any, error) {
+	switch op.Type {
+	case lexer.EQUAL, lexer.NOT_EQUAL:
+		return equality(op, l, r)
+	case lexer.GREATER, lexer.GREATER_EQUAL, lexer.LESS, lexer.LESS_EQUAL:
+		return comparison(op, l, r)
+	case lexer.SHIFT_LEFT, lexer.SHIFT_RIGHT, lexer.ROUNDSHIFT_LEFT, lexer.ROUNDSHIFT_RIGHT:
+		return bitshift(op, l, r)
+	case lexer.AND_BITWISE, lexer.OR_BITWISE, lexer.XOR_BITWISE, lexer.NAND_BITWISE, lexer.NOR_BITWISE, lexer.XNOR_BITWISE:
+		return bitwise(op, l, r)
+	case lexer.PLUS, lexer.MINUS:
+		return term(op, l, r)
+	case lexer.STAR, lexer.SLASH, lexer.MOD:
+		return factor(op, l, r)
+	case lexer.POW:
+		return power(op, l, r)
+	default:
+		return nil, e.Error(op.Line, op.Column, op.Lexeme, e.RUNTIME, "unknown operator")
+	}
+}
+
+func (s *Scope) EqualityEval(eq Equality) (any, error) {
+	l, r, err := s.operands(eq.Left, eq.Right)
 	if err != nil {
-		return
+		return nil, err
 	}
 
+	return equality(eq.Operator, l, r)
+}
+
+func equality(op lexer.Token, l any, r any) (res any, err error) {
 	l, r, precedence := typePrecedence(l, r, true)
+	if isSized(precedence) {
+		return sized(op, precedence, l, r, equality)
+	}
 	if precedence == UNKNOWN {
-		err = e.Error(eq.Operator.Line, eq.Operator.Column, eq.Operator.Lexeme, e.RUNTIME, "invalid operands")
+		err = e.Error(op.Line, op.Column, op.Lexeme, e.RUNTIME, "invalid operands")
 	}
 
-	switch eq.Operator.Type {
+	switch op.Type {
 	case lexer.EQUAL:
 		switch precedence {
 		case BOOL:
@@ -268,6 +333,10 @@ func (s *Scope) EqualityEval(eq Equality) (res any, err error) {
 			res = l.(float64) == r.(float64)
 		case STRING:
 			res = l.(string) == r.(string)
+		case ERR:
+			res = l.(Err).Kind == r.(Err).Kind
+		case POINTER:
+			res = l.(Address).same(r.(Address))
 		}
 	case lexer.NOT_EQUAL:
 		switch precedence {
@@ -283,29 +352,35 @@ func (s *Scope) EqualityEval(eq Equality) (res any, err error) {
 			res = l.(float64) != r.(float64)
 		case STRING:
 			res = l.(string) != r.(string)
+		case ERR:
+			res = l.(Err).Kind != r.(Err).Kind
+		case POINTER:
+			res = !l.(Address).same(r.(Address))
 		}
 	}
 
 	return
 }
 
-func (s *Scope) ComparisonEval(c Comparison) (res any, err error) {
-	l, err := s.evaluate(c.Left)
+func (s *Scope) ComparisonEval(c Comparison) (any, error) {
+	l, r, err := s.operands(c.Left, c.Right)
 	if err != nil {
-		return
+		return nil, err
 	}
 
-	r, err := s.evaluate(c.Right)
-	if err != nil {
-		return
-	}
+	return comparison(c.Operator, l, r)
+}
 
+func comparison(op lexer.Token, l any, r any) (res any, err error) {
 	l, r, precedence := typePrecedence(l, r, true)
+	if isSized(precedence) {
+		return sized(op, precedence, l, r, comparison)
+	}
 	if precedence == UNKNOWN {
-		err = e.Error(c.Operator.Line, c.Operator.Column, c.Operator.Lexeme, e.RUNTIME, "invalid operands")
+		err = e.Error(op.Line, op.Column, op.Lexeme, e.RUNTIME, "invalid operands")
 	}
 
-	switch c.Operator.Type {
+	switch op.Type {
 	case lexer.GREATER_EQUAL:
 		switch precedence {
 		case BOOL:
@@ -319,7 +394,7 @@ func (s *Scope) ComparisonEval(c Comparison) (res any, err error) {
 		case FLOAT:
 			res = l.(float64) >= r.(float64)
 		case STRING:
-			err = e.Error(c.Operator.Line, c.Operator.Column, c.Operator.Lexeme, e.RUNTIME, "cannot direct compare strings")
+			err = e.Error(op.Line, op.Column, op.Lexeme, e.RUNTIME, "cannot direct compare strings")
 		}
 	case lexer.LESS_EQUAL:
 		switch precedence {
@@ -334,7 +409,7 @@ func (s *Scope) ComparisonEval(c Comparison) (res any, err error) {
 		case FLOAT:
 			res = l.(float64) <= r.(float64)
 		case STRING:
-			err = e.Error(c.Operator.Line, c.Operator.Column, c.Operator.Lexeme, e.RUNTIME, "cannot direct compare strings")
+			err = e.Error(op.Line, op.Column, op.Lexeme, e.RUNTIME, "cannot direct compare strings")
 		}
 	case lexer.GREATER:
 		switch precedence {
@@ -349,7 +424,7 @@ func (s *Scope) ComparisonEval(c Comparison) (res any, err error) {
 		case FLOAT:
 			res = l.(float64) > r.(float64)
 		case STRING:
-			err = e.Error(c.Operator.Line, c.Operator.Column, c.Operator.Lexeme, e.RUNTIME, "cannot direct compare strings")
+			err = e.Error(op.Line, op.Column, op.Lexeme, e.RUNTIME, "cannot direct compare strings")
 		}
 	case lexer.LESS:
 		switch precedence {
@@ -364,103 +439,114 @@ func (s *Scope) ComparisonEval(c Comparison) (res any, err error) {
 		case FLOAT:
 			res = l.(float64) < r.(float64)
 		case STRING:
-			err = e.Error(c.Operator.Line, c.Operator.Column, c.Operator.Lexeme, e.RUNTIME, "cannot direct compare strings")
+			err = e.Error(op.Line, op.Column, op.Lexeme, e.RUNTIME, "cannot direct compare strings")
 		}
 	}
 
 	return
 }
 
-func (s *Scope) BitshiftEval(b Bitshift) (res any, err error) {
-	l, err := s.evaluate(b.Left)
+func (s *Scope) BitshiftEval(b Bitshift) (any, error) {
+	l, r, err := s.operands(b.Left, b.Right)
 	if err != nil {
-		return
+		return nil, err
 	}
 
-	r, err := s.evaluate(b.Right)
-	if err != nil {
-		return
+	return bitshift(b.Operator, l, r)
+}
+
+func bitshift(op lexer.Token, l any, r any) (res any, err error) {
+	// a sized integer shifts inside its own width
+	if t := getType(l); isSized(t) && t != F32 {
+		if op.Type == lexer.ROUNDSHIFT_LEFT || op.Type == lexer.ROUNDSHIFT_RIGHT {
+			return rotate(op, l, toUint(widen(r))), nil
+		}
+		return sized(op, t, l, nil, func(op lexer.Token, l any, _ any) (any, error) {
+			return bitshift(op, l, widen(r))
+		})
 	}
 
 	if _, _, precedence := typePrecedence(l, r, true); precedence == UNKNOWN {
-		err = e.Error(b.Operator.Line, b.Operator.Column, b.Operator.Lexeme, e.RUNTIME, "invalid operands")
+		err = e.Error(op.Line, op.Column, op.Lexeme, e.RUNTIME, "invalid operands")
 	}
 
-	switch b.Operator.Type {
+	switch op.Type {
 	case lexer.SHIFT_LEFT:
 		switch getType(l) {
 		case BOOL:
-			err = e.Error(b.Operator.Line, b.Operator.Column, b.Operator.Lexeme, e.RUNTIME, "cannot bitshift bool")
+			err = e.Error(op.Line, op.Column, op.Lexeme, e.RUNTIME, "cannot bitshift bool")
 		case INT:
 			res = l.(int) << toUint(r)
 		case UINT:
 			res = l.(uint) << toUint(r)
 		case FLOAT:
-			err = e.Error(b.Operator.Line, b.Operator.Column, b.Operator.Lexeme, e.RUNTIME, "cannot bitshift float")
+			err = e.Error(op.Line, op.Column, op.Lexeme, e.RUNTIME, "cannot bitshift float")
 		case STRING:
-			err = e.Error(b.Operator.Line, b.Operator.Column, b.Operator.Lexeme, e.RUNTIME, "cannot bitshift strings")
+			err = e.Error(op.Line, op.Column, op.Lexeme, e.RUNTIME, "cannot bitshift strings")
 		}
 	case lexer.ROUNDSHIFT_LEFT:
 		switch getType(l) {
 		case BOOL:
-			err = e.Error(b.Operator.Line, b.Operator.Column, b.Operator.Lexeme, e.RUNTIME, "cannot roundshift bool")
+			err = e.Error(op.Line, op.Column, op.Lexeme, e.RUNTIME, "cannot roundshift bool")
 		case INT:
 			res = RotateLeftInt(l.(int), toUint(r))
 		case UINT:
 			res = RotateLeftUint(l.(uint), toUint(r))
 		case FLOAT:
-			err = e.Error(b.Operator.Line, b.Operator.Column, b.Operator.Lexeme, e.RUNTIME, "cannot roundshift float")
+			err = e.Error(op.Line, op.Column, op.Lexeme, e.RUNTIME, "cannot roundshift float")
 		case STRING:
-			err = e.Error(b.Operator.Line, b.Operator.Column, b.Operator.Lexeme, e.RUNTIME, "cannot roundshift strings")
+			err = e.Error(op.Line, op.Column, op.Lexeme, e.RUNTIME, "cannot roundshift strings")
 		}
 	case lexer.SHIFT_RIGHT:
 		switch getType(l) {
 		case BOOL:
-			err = e.Error(b.Operator.Line, b.Operator.Column, b.Operator.Lexeme, e.RUNTIME, "cannot bitshift bool")
+			err = e.Error(op.Line, op.Column, op.Lexeme, e.RUNTIME, "cannot bitshift bool")
 		case INT:
 			res = l.(int) >> toUint(r)
 		case UINT:
 			res = l.(uint) >> toUint(r)
 		case FLOAT:
-			err = e.Error(b.Operator.Line, b.Operator.Column, b.Operator.Lexeme, e.RUNTIME, "cannot bitshift float")
+			err = e.Error(op.Line, op.Column, op.Lexeme, e.RUNTIME, "cannot bitshift float")
 		case STRING:
-			err = e.Error(b.Operator.Line, b.Operator.Column, b.Operator.Lexeme, e.RUNTIME, "cannot bitshift strings")
+			err = e.Error(op.Line, op.Column, op.Lexeme, e.RUNTIME, "cannot bitshift strings")
 		}
 	case lexer.ROUNDSHIFT_RIGHT:
 		switch getType(l) {
 		case BOOL:
-			err = e.Error(b.Operator.Line, b.Operator.Column, b.Operator.Lexeme, e.RUNTIME, "cannot roundshift bool")
+			err = e.Error(op.Line, op.Column, op.Lexeme, e.RUNTIME, "cannot roundshift bool")
 		case INT:
 			res = RotateRightInt(l.(int), toUint(r))
 		case UINT:
 			res = RotateRightUint(l.(uint), toUint(r))
 		case FLOAT:
-			err = e.Error(b.Operator.Line, b.Operator.Column, b.Operator.Lexeme, e.RUNTIME, "cannot roundshift float")
+			err = e.Error(op.Line, op.Column, op.Lexeme, e.RUNTIME, "cannot roundshift float")
 		case STRING:
-			err = e.Error(b.Operator.Line, b.Operator.Column, b.Operator.Lexeme, e.RUNTIME, "cannot roundshift strings")
+			err = e.Error(op.Line, op.Column, op.Lexeme, e.RUNTIME, "cannot roundshift strings")
 		}
 	}
 
 	return
 }
 
-func (s *Scope) BitwiseEval(b Bitwise) (res any, err error) {
-	l, err := s.evaluate(b.Left)
+func (s *Scope) BitwiseEval(b Bitwise) (any, error) {
+	l, r, err := s.operands(b.Left, b.Right)
 	if err != nil {
-		return
+		return nil, err
 	}
 
-	r, err := s.evaluate(b.Right)
-	if err != nil {
-		return
-	}
+	return bitwise(b.Operator, l, r)
+}
 
+func bitwise(op lexer.Token, l any, r any) (res any, err error) {
 	l, r, precedence := typePrecedence(l, r, false)
+	if isSized(precedence) {
+		return sized(op, precedence, l, r, bitwise)
+	}
 	if precedence == UNKNOWN {
-		err = e.Error(b.Operator.Line, b.Operator.Column, b.Operator.Lexeme, e.RUNTIME, "invalid operands")
+		err = e.Error(op.Line, op.Column, op.Lexeme, e.RUNTIME, "invalid operands")
 	}
 
-	switch b.Operator.Type {
+	switch op.Type {
 	case lexer.AND_BITWISE:
 		switch precedence {
 		case BOOL:
@@ -470,9 +556,9 @@ func (s *Scope) BitwiseEval(b Bitwise) (res any, err error) {
 		case INT:
 			res = l.(int) & r.(int)
 		case FLOAT:
-			err = e.Error(b.Operator.Line, b.Operator.Column, b.Operator.Lexeme, e.RUNTIME, "cannot bitwise float")
+			err = e.Error(op.Line, op.Column, op.Lexeme, e.RUNTIME, "cannot bitwise float")
 		case STRING:
-			err = e.Error(b.Operator.Line, b.Operator.Column, b.Operator.Lexeme, e.RUNTIME, "cannot bitwise strings")
+			err = e.Error(op.Line, op.Column, op.Lexeme, e.RUNTIME, "cannot bitwise strings")
 		}
 	case lexer.OR_BITWISE:
 		switch precedence {
@@ -483,9 +569,9 @@ func (s *Scope) BitwiseEval(b Bitwise) (res any, err error) {
 		case INT:
 			res = l.(int) | r.(int)
 		case FLOAT:
-			err = e.Error(b.Operator.Line, b.Operator.Column, b.Operator.Lexeme, e.RUNTIME, "cannot bitwise float")
+			err = e.Error(op.Line, op.Column, op.Lexeme, e.RUNTIME, "cannot bitwise float")
 		case STRING:
-			err = e.Error(b.Operator.Line, b.Operator.Column, b.Operator.Lexeme, e.RUNTIME, "cannot bitwise strings")
+			err = e.Error(op.Line, op.Column, op.Lexeme, e.RUNTIME, "cannot bitwise strings")
 		}
 	case lexer.XOR_BITWISE:
 		switch precedence {
@@ -496,9 +582,9 @@ func (s *Scope) BitwiseEval(b Bitwise) (res any, err error) {
 		case INT:
 			res = l.(int) ^ r.(int)
 		case FLOAT:
-			err = e.Error(b.Operator.Line, b.Operator.Column, b.Operator.Lexeme, e.RUNTIME, "cannot bitwise float")
+			err = e.Error(op.Line, op.Column, op.Lexeme, e.RUNTIME, "cannot bitwise float")
 		case STRING:
-			err = e.Error(b.Operator.Line, b.Operator.Column, b.Operator.Lexeme, e.RUNTIME, "cannot bitwise strings")
+			err = e.Error(op.Line, op.Column, op.Lexeme, e.RUNTIME, "cannot bitwise strings")
 		}
 	case lexer.NAND_BITWISE:
 		switch precedence {
@@ -509,9 +595,9 @@ func (s *Scope) BitwiseEval(b Bitwise) (res any, err error) {
 		case INT:
 			res = ^(l.(int) & r.(int))
 		case FLOAT:
-			err = e.Error(b.Operator.Line, b.Operator.Column, b.Operator.Lexeme, e.RUNTIME, "cannot bitwise float")
+			err = e.Error(op.Line, op.Column, op.Lexeme, e.RUNTIME, "cannot bitwise float")
 		case STRING:
-			err = e.Error(b.Operator.Line, b.Operator.Column, b.Operator.Lexeme, e.RUNTIME, "cannot bitwise strings")
+			err = e.Error(op.Line, op.Column, op.Lexeme, e.RUNTIME, "cannot bitwise strings")
 		}
 	case lexer.NOR_BITWISE:
 		switch precedence {
@@ -522,9 +608,9 @@ func (s *Scope) BitwiseEval(b Bitwise) (res any, err error) {
 		case INT:
 			res = ^(l.(int) | r.(int))
 		case FLOAT:
-			err = e.Error(b.Operator.Line, b.Operator.Column, b.Operator.Lexeme, e.RUNTIME, "cannot bitwise float")
+			err = e.Error(op.Line, op.Column, op.Lexeme, e.RUNTIME, "cannot bitwise float")
 		case STRING:
-			err = e.Error(b.Operator.Line, b.Operator.Column, b.Operator.Lexeme, e.RUNTIME, "cannot bitwise strings")
+			err = e.Error(op.Line, op.Column, op.Lexeme, e.RUNTIME, "cannot bitwise strings")
 		}
 	case lexer.XNOR_BITWISE:
 		switch precedence {
@@ -535,42 +621,44 @@ func (s *Scope) BitwiseEval(b Bitwise) (res any, err error) {
 		case INT:
 			res = ^(l.(int) ^ r.(int))
 		case FLOAT:
-			err = e.Error(b.Operator.Line, b.Operator.Column, b.Operator.Lexeme, e.RUNTIME, "cannot bitwise float")
+			err = e.Error(op.Line, op.Column, op.Lexeme, e.RUNTIME, "cannot bitwise float")
 		case STRING:
-			err = e.Error(b.Operator.Line, b.Operator.Column, b.Operator.Lexeme, e.RUNTIME, "cannot bitwise strings")
+			err = e.Error(op.Line, op.Column, op.Lexeme, e.RUNTIME, "cannot bitwise strings")
 		}
 	}
 
 	return
 }
 
-func (s *Scope) TermEval(t Term) (res any, err error) {
-	l, err := s.evaluate(t.Left)
+func (s *Scope) TermEval(t Term) (any, error) {
+	l, r, err := s.operands(t.Left, t.Right)
 	if err != nil {
-		return
+		return nil, err
 	}
 
-	r, err := s.evaluate(t.Right)
-	if err != nil {
-		return
-	}
+	return term(t.Operator, l, r)
+}
 
+func term(op lexer.Token, l any, r any) (res any, err error) {
 	l, r, precedence := typePrecedence(l, r, false)
+	if isSized(precedence) {
+		return sized(op, precedence, l, r, term)
+	}
 	if precedence == UNKNOWN {
-		err = e.Error(t.Operator.Line, t.Operator.Column, t.Operator.Lexeme, e.RUNTIME, "cannot convert operands")
+		err = e.Error(op.Line, op.Column, op.Lexeme, e.RUNTIME, "cannot convert operands")
 	}
 
-	switch t.Operator.Type {
+	switch op.Type {
 	case lexer.PLUS:
 		switch precedence {
 		case BOOL:
-			err = e.Error(t.Operator.Line, t.Operator.Column, t.Operator.Lexeme, e.RUNTIME, "cannot sum bool values")
+			err = e.Error(op.Line, op.Column, op.Lexeme, e.RUNTIME, "cannot sum bool values")
 		case CHAR:
 			res = string(l.(rune)) + string(r.(rune))
 		case UINT:
-			res = l.(uint) + r.(uint)
+			res, err = unsigned(op, l, r)
 		case INT:
-			res = l.(int) + r.(int)
+			res, err = signed(op, l, r)
 		case FLOAT:
 			res = l.(float64) + r.(float64)
 		case STRING:
@@ -579,143 +667,179 @@ func (s *Scope) TermEval(t Term) (res any, err error) {
 	case lexer.MINUS:
 		switch precedence {
 		case BOOL:
-			err = e.Error(t.Operator.Line, t.Operator.Column, t.Operator.Lexeme, e.RUNTIME, "cannot sum bool values")
+			err = e.Error(op.Line, op.Column, op.Lexeme, e.RUNTIME, "cannot sum bool values")
 		case UINT:
-			res = l.(uint) - r.(uint)
+			res, err = unsigned(op, l, r)
 		case INT:
-			res = l.(int) - r.(int)
+			res, err = signed(op, l, r)
 		case FLOAT:
 			res = l.(float64) - r.(float64)
 		case STRING:
-			err = e.Error(t.Operator.Line, t.Operator.Column, t.Operator.Lexeme, e.RUNTIME, "cannot subtract strings")
+			err = e.Error(op.Line, op.Column, op.Lexeme, e.RUNTIME, "cannot subtract strings")
 		}
 	}
 
 	return
 }
 
-func (s *Scope) FactorEval(t Factor) (res any, err error) {
-	l, err := s.evaluate(t.Left)
-	if err != nil {
-		return
+// signed runs an operator on two ints, a result that does not fit in an int overflows
+func signed(op lexer.Token, l any, r any) (any, error) {
+	n, ok := IntOp(op.Type, l.(int), r.(int))
+	if !ok {
+		return nil, overflow(op, l, r, INT)
 	}
+	return n, nil
+}
 
-	r, err := s.evaluate(t.Right)
+// unsigned runs an operator on two uints, a result below 0 or too big overflows
+func unsigned(op lexer.Token, l any, r any) (any, error) {
+	n, ok := uintOp(op.Type, l.(uint), r.(uint))
+	if !ok {
+		return nil, overflow(op, l, r, UINT)
+	}
+	return n, nil
+}
+
+func (s *Scope) FactorEval(t Factor) (any, error) {
+	l, r, err := s.operands(t.Left, t.Right)
 	if err != nil {
-		return
+		return nil, err
 	}
 
+	return factor(t.Operator, l, r)
+}
+
+func factor(op lexer.Token, l any, r any) (res any, err error) {
 	l, r, precedence := typePrecedence(l, r, false)
+	if isSized(precedence) {
+		return sized(op, precedence, l, r, factor)
+	}
 	if precedence == UNKNOWN {
-		err = e.Error(t.Operator.Line, t.Operator.Column, t.Operator.Lexeme, e.RUNTIME, "cannot implicit convert operands")
+		err = e.Error(op.Line, op.Column, op.Lexeme, e.RUNTIME, "cannot implicit convert operands")
 	}
 
-	switch t.Operator.Type {
+	switch op.Type {
 	case lexer.STAR:
 		switch precedence {
 		case BOOL:
-			err = e.Error(t.Operator.Line, t.Operator.Column, t.Operator.Lexeme, e.RUNTIME, "cannot multiply bool values")
+			err = e.Error(op.Line, op.Column, op.Lexeme, e.RUNTIME, "cannot multiply bool values")
 		case UINT:
-			res = l.(uint) * r.(uint)
+			res, err = unsigned(op, l, r)
 		case INT:
-			res = l.(int) * r.(int)
+			res, err = signed(op, l, r)
 		case FLOAT:
 			res = l.(float64) * r.(float64)
 		case STRING:
-			err = e.Error(t.Operator.Line, t.Operator.Column, t.Operator.Lexeme, e.RUNTIME, "cannot multiply string values")
+			err = e.Error(op.Line, op.Column, op.Lexeme, e.RUNTIME, "cannot multiply string values")
 		}
 	case lexer.SLASH:
 		switch precedence {
 		case BOOL:
-			err = e.Error(t.Operator.Line, t.Operator.Column, t.Operator.Lexeme, e.RUNTIME, "cannot divide bool values")
+			err = e.Error(op.Line, op.Column, op.Lexeme, e.RUNTIME, "cannot divide bool values")
 		case UINT:
 			if r.(uint) == 0 {
-				err = e.Error(t.Operator.Line, t.Operator.Column, t.Operator.Lexeme, e.RUNTIME, "division by zero")
+				err = e.Error(op.Line, op.Column, op.Lexeme, e.RUNTIME, "division by zero")
 			} else {
 				res = l.(uint) / r.(uint)
 			}
 		case INT:
 			if r.(int) == 0 {
-				err = e.Error(t.Operator.Line, t.Operator.Column, t.Operator.Lexeme, e.RUNTIME, "division by zero")
+				err = e.Error(op.Line, op.Column, op.Lexeme, e.RUNTIME, "division by zero")
 			} else {
-				res = l.(int) / r.(int)
+				res, err = signed(op, l, r)
 			}
 		case FLOAT:
 			if r.(float64) == 0 {
-				err = e.Error(t.Operator.Line, t.Operator.Column, t.Operator.Lexeme, e.RUNTIME, "division by zero")
+				err = e.Error(op.Line, op.Column, op.Lexeme, e.RUNTIME, "division by zero")
 			} else {
 				res = l.(float64) / r.(float64)
 			}
 		case STRING:
-			err = e.Error(t.Operator.Line, t.Operator.Column, t.Operator.Lexeme, e.RUNTIME, "cannot divide strings")
+			err = e.Error(op.Line, op.Column, op.Lexeme, e.RUNTIME, "cannot divide strings")
 		}
 	case lexer.MOD:
 		switch precedence {
 		case BOOL:
-			err = e.Error(t.Operator.Line, t.Operator.Column, t.Operator.Lexeme, e.RUNTIME, "cannot mod bool values")
+			err = e.Error(op.Line, op.Column, op.Lexeme, e.RUNTIME, "cannot mod bool values")
 		case UINT:
 			res = l.(uint) % r.(uint)
 		case INT:
 			res = l.(int) % r.(int)
 		case FLOAT:
-			err = e.Error(t.Operator.Line, t.Operator.Column, t.Operator.Lexeme, e.RUNTIME, "cannot mod float values")
+			err = e.Error(op.Line, op.Column, op.Lexeme, e.RUNTIME, "cannot mod float values")
 		case STRING:
-			err = e.Error(t.Operator.Line, t.Operator.Column, t.Operator.Lexeme, e.RUNTIME, "cannot mod strings")
+			err = e.Error(op.Line, op.Column, op.Lexeme, e.RUNTIME, "cannot mod strings")
 		}
 	}
 
 	return
 }
 
-func (s *Scope) PowerEval(p Power) (res any, err error) {
-	l, err := s.evaluate(p.Left)
+func (s *Scope) PowerEval(p Power) (any, error) {
+	l, r, err := s.operands(p.Left, p.Right)
 	if err != nil {
-		return
+		return nil, err
 	}
 
-	r, err := s.evaluate(p.Right)
-	if err != nil {
-		return
-	}
+	return power(p.Operator, l, r)
+}
 
+func power(op lexer.Token, l any, r any) (res any, err error) {
 	l, r, precedence := typePrecedence(l, r, false)
+	if isSized(precedence) {
+		return sized(op, precedence, l, r, power)
+	}
 	if precedence == UNKNOWN {
-		err = e.Error(p.Operator.Line, p.Operator.Column, p.Operator.Lexeme, e.RUNTIME, "cannot implicit convert operands")
+		err = e.Error(op.Line, op.Column, op.Lexeme, e.RUNTIME, "cannot implicit convert operands")
 	}
 
-	switch p.Operator.Type {
+	switch op.Type {
 	case lexer.POW:
 		switch precedence {
 		case BOOL:
-			err = e.Error(p.Operator.Line, p.Operator.Column, p.Operator.Lexeme, e.RUNTIME, "cannot power bool values")
+			err = e.Error(op.Line, op.Column, op.Lexeme, e.RUNTIME, "cannot power bool values")
 		case UINT:
-			res = uint(math.Pow(float64(l.(uint)), float64(r.(uint))))
+			res, err = unsigned(op, l, r)
 		case INT:
-			res = int(math.Pow(float64(l.(int)), float64(r.(int))))
+			if r.(int) < 0 {
+				res = int(math.Pow(float64(l.(int)), float64(r.(int))))
+			} else {
+				res, err = signed(op, l, r)
+			}
 		case FLOAT:
 			res = math.Pow(l.(float64), r.(float64))
 		case STRING:
-			err = e.Error(p.Operator.Line, p.Operator.Column, p.Operator.Lexeme, e.RUNTIME, "cannot power string values")
+			err = e.Error(op.Line, op.Column, op.Lexeme, e.RUNTIME, "cannot power string values")
 		}
 	}
 
 	return
 }
 
-func (s *Scope) UnaryEval(u Unary) (res any, err error) {
+func (s *Scope) UnaryEval(u Unary) (any, error) {
 	v, err := s.evaluate(u.Right)
 	if err != nil {
 		return nil, err
 	}
 
+	return UnaryOp(u.Operator, v)
+}
+
+func UnaryOp(o lexer.Token, v any) (res any, err error) {
 	t := getType(v)
-	o := u.Operator
+	if isSized(t) && o.Type != lexer.GO_IN {
+		return sized(o, t, v, nil, func(o lexer.Token, v any, _ any) (any, error) {
+			return UnaryOp(o, v)
+		})
+	}
 
 	switch o.Type {
 	case lexer.BANG:
 		switch t {
 		case INT:
 			res = utils.Ternary(v.(int) == 0, true, false)
+		case UINT:
+			res = v.(uint) == 0
 		case BOOL:
 			res = !v.(bool)
 		case FLOAT:
@@ -729,6 +853,8 @@ func (s *Scope) UnaryEval(u Unary) (res any, err error) {
 		switch t {
 		case INT:
 			res = ^v.(int)
+		case UINT:
+			res = ^v.(uint)
 		case BOOL:
 			res = !v.(bool)
 		case FLOAT:
@@ -741,7 +867,13 @@ func (s *Scope) UnaryEval(u Unary) (res any, err error) {
 	case lexer.MINUS:
 		switch t {
 		case INT:
-			res = -v.(int)
+			if res = -v.(int); v.(int) == math.MinInt {
+				err = overflow(o, v, nil, INT)
+			}
+		case UINT:
+			if res = -v.(uint); v.(uint) != 0 {
+				err = overflow(o, v, nil, UINT)
+			}
 		case BOOL:
 			err = e.Error(o.Line, o.Column, o.Lexeme, e.RUNTIME, "operator - not defined on bool")
 		case FLOAT:
@@ -750,7 +882,11 @@ func (s *Scope) UnaryEval(u Unary) (res any, err error) {
 			return nil, e.Error(o.Line, o.Column, o.Lexeme, e.RUNTIME, "expect number after ~")
 		}
 	case lexer.GO_IN:
-		err = e.Error(o.Line, o.Column, o.Lexeme, e.RUNTIME, "parallelism not yet implemented")
+		c, ok := v.(Channel)
+		if !ok {
+			return nil, e.Error(o.Line, o.Column, o.Lexeme, e.RUNTIME, fmt.Sprintf("expect a channel after <!, found: %v", v))
+		}
+		return c.Receive(o)
 	default:
 		err = e.Error(o.Line, o.Column, o.Lexeme, e.RUNTIME, "unknown operator")
 	}
@@ -758,19 +894,24 @@ func (s *Scope) UnaryEval(u Unary) (res any, err error) {
 	return res, err
 }
 
-func (s *Scope) CastEval(c Cast) (res any, err error) {
-	l, err := s.evaluate(c.Left)
+func (s *Scope) CastEval(c Cast) (any, error) {
+	l, r, err := s.operands(c.Left, c.TypeCast)
 	if err != nil {
 		return nil, err
 	}
 
-	r, err := s.evaluate(c.TypeCast)
-	if err != nil {
-		return nil, err
-	}
+	return CastOp(c.Operator, l, r)
+}
 
+// CastOp converts l to the Type found in r
+func CastOp(op lexer.Token, l any, r any) (res any, err error) {
 	switch t := r.(type) {
 	case Type:
+		// a sized number converts as its family, but prints as itself
+		if t.Name.Type != lexer.STRING {
+			l = widen(l)
+		}
+
 		switch t.Name.Type {
 		case lexer.BOOL:
 			switch getType(l) {
@@ -785,9 +926,9 @@ func (s *Scope) CastEval(c Cast) (res any, err error) {
 			case STRING:
 				res = len(l.(string)) == 0
 			default:
-				err = e.Error(c.Operator.Line, c.Operator.Column, c.Operator.Lexeme, e.RUNTIME, "cannot convert type to bool")
+				err = e.Error(op.Line, op.Column, op.Lexeme, e.RUNTIME, "cannot convert type to bool")
 			}
-		case lexer.INT:
+		case lexer.INT, lexer.I64:
 			switch getType(l) {
 			case BOOL:
 				res = boolToInt(l.(bool))
@@ -798,11 +939,11 @@ func (s *Scope) CastEval(c Cast) (res any, err error) {
 			case FLOAT:
 				res = int(l.(float64))
 			case STRING:
-				err = e.Error(c.Operator.Line, c.Operator.Column, c.Operator.Lexeme, e.RUNTIME, "cannot conver string to int")
+				err = e.Error(op.Line, op.Column, op.Lexeme, e.RUNTIME, "cannot conver string to int")
 			default:
-				err = e.Error(c.Operator.Line, c.Operator.Column, c.Operator.Lexeme, e.RUNTIME, "cannot convert type to int")
+				err = e.Error(op.Line, op.Column, op.Lexeme, e.RUNTIME, "cannot convert type to int")
 			}
-		case lexer.UINT:
+		case lexer.UINT, lexer.U64:
 			switch getType(l) {
 			case BOOL:
 				res = uint(boolToInt(l.(bool)))
@@ -813,11 +954,11 @@ func (s *Scope) CastEval(c Cast) (res any, err error) {
 			case FLOAT:
 				res = uint(l.(float64))
 			case STRING:
-				err = e.Error(c.Operator.Line, c.Operator.Column, c.Operator.Lexeme, e.RUNTIME, "cannot conver string to uint")
+				err = e.Error(op.Line, op.Column, op.Lexeme, e.RUNTIME, "cannot conver string to uint")
 			default:
-				err = e.Error(c.Operator.Line, c.Operator.Column, c.Operator.Lexeme, e.RUNTIME, "cannot convert type to uint")
+				err = e.Error(op.Line, op.Column, op.Lexeme, e.RUNTIME, "cannot convert type to uint")
 			}
-		case lexer.FLOAT:
+		case lexer.FLOAT, lexer.F64:
 			switch getType(l) {
 			case BOOL:
 				res = float64(boolToInt(l.(bool)))
@@ -828,9 +969,9 @@ func (s *Scope) CastEval(c Cast) (res any, err error) {
 			case FLOAT:
 				res = l
 			case STRING:
-				err = e.Error(c.Operator.Line, c.Operator.Column, c.Operator.Lexeme, e.RUNTIME, "cannot conver string to float")
+				err = e.Error(op.Line, op.Column, op.Lexeme, e.RUNTIME, "cannot conver string to float")
 			default:
-				err = e.Error(c.Operator.Line, c.Operator.Column, c.Operator.Lexeme, e.RUNTIME, "cannot convert type to float")
+				err = e.Error(op.Line, op.Column, op.Lexeme, e.RUNTIME, "cannot convert type to float")
 			}
 		case lexer.STRING:
 			switch getType(l) {
@@ -848,23 +989,768 @@ func (s *Scope) CastEval(c Cast) (res any, err error) {
 			case STRING:
 				res = rune(l.(string)[0])
 			default:
-				err = e.Error(c.Operator.Line, c.Operator.Column, c.Operator.Lexeme, e.RUNTIME, "???????????????")
+				err = e.Error(op.Line, op.Column, op.Lexeme, e.RUNTIME, "???????????????")
 			}
+		case lexer.I8, lexer.I16, lexer.I32, lexer.U8, lexer.U16, lexer.U32, lexer.F32, lexer.BYTE:
+			res, err = cast(op, l, tokenToType(t.Name))
 		default:
-			err = e.Error(c.Operator.Line, c.Operator.Column, c.Operator.Lexeme, e.RUNTIME, "cannot convert to type")
+			err = e.Error(op.Line, op.Column, op.Lexeme, e.RUNTIME, "cannot convert to type")
 		}
 	default:
-		err = e.Error(c.Operator.Line, c.Operator.Column, c.Operator.Lexeme, e.RUNTIME, "type of typecast not found")
+		err = e.Error(op.Line, op.Column, op.Lexeme, e.RUNTIME, "type of typecast not found")
 	}
 
 	return res, err
 }
 
+func (s *Scope) MultipleEval(m Multiple) (any, error) {
+	values := make(Tuple, 0, len(m.Values))
+	for _, x := range m.Values {
+		v, err := s.evaluate(x)
+		if err != nil {
+			return nil, err
+		}
+		values = append(values, v)
+	}
+	return values, nil
+}
+
+func (s *Scope) CallEval(c Call) (any, error) {
+	callee, err := s.evaluate(c.Callee)
+	if err != nil {
+		return nil, err
+	}
+
+	args := make([]any, 0, len(c.Args))
+	for _, a := range c.Args {
+		v, err := s.evaluate(a)
+		if err != nil {
+			return nil, err
+		}
+		args = append(args, v)
+	}
+
+	switch f := callee.(type) {
+	case Function:
+		return s.call(f, args, c.Token)
+	case Native:
+		return f.Call(args, c.Token)
+	default:
+		return nil, e.Error(c.Token.Line, c.Token.Column, c.Token.Lexeme, e.RUNTIME, fmt.Sprintf("%v is not a function", callee))
+	}
+}
+
+func (s *Scope) ObjectLiteralEval(o ObjectLiteral) (any, error) {
+	v, err := s.evaluate(o.Object)
+	if err != nil {
+		return nil, err
+	}
+
+	object, ok := v.(Object)
+	if !ok {
+		n := o.Object.Name
+		return nil, e.Error(n.Line, n.Column, n.Lexeme, e.RUNTIME, fmt.Sprintf("%s is not an obj", n.Lexeme))
+	}
+
+	values, err := s.values(o.Values)
+	if err != nil {
+		return nil, err
+	}
+
+	return object.New(o.Object.Name, o.Names, values)
+}
+
+func (s *Scope) AccessEval(a Access) (any, error) {
+	v, _, err := s.navigate(a)
+	return v, err
+}
+
+// link evaluates a step of a navigation chain, skipped tells a `?.` before it found nil,
+// so the whole chain gives nil
+func (s *Scope) link(x Expr) (v any, skipped bool, err error) {
+	switch step := x.(type) {
+	case Access:
+		return s.navigate(step)
+	case PositionAccess:
+		collection, skipped, err := s.link(step.Expression)
+		if err != nil || skipped {
+			return nil, skipped, err
+		}
+		pos, err := s.evaluate(step.Pos)
+		if err != nil {
+			return nil, false, err
+		}
+		v, err = s.Position(step, collection, pos)
+		return v, false, err
+	default:
+		v, err = s.evaluate(x)
+		return v, false, err
+	}
+}
+
+// A method is called with its arguments, or without them when it takes none, `p.greet` is
+// the same as `p.greet()`. A method that takes arguments but got none is just its value
+func (s *Scope) navigate(a Access) (any, bool, error) {
+	left, skipped, err := s.link(a.Left)
+	if err != nil || skipped {
+		return nil, skipped, err
+	}
+	v, skipped, err := s.Member(a, left)
+	if err != nil || skipped {
+		return nil, skipped, err
+	}
+
+	name := a.Name()
+	c, isCall := a.Right.(Call)
+	f, isMethod := v.(Function)
+	if !isCall && !(isMethod && f.Arity() == 0) {
+		return v, false, nil
+	}
+
+	var args []any
+	if isCall {
+		if args, err = s.values(c.Args); err != nil {
+			return nil, false, err
+		}
+	}
+
+	switch f := v.(type) {
+	case Function:
+		v, err = s.call(f, args, name)
+	case Native:
+		v, err = f.Call(args, name)
+	default:
+		err = e.Error(name.Line, name.Column, name.Lexeme, e.RUNTIME, fmt.Sprintf("%v is not a function", v))
+	}
+	return v, false, err
+}
+
+// Fields change in place, so the variable holding the instance has to be mutable, and the obj decides
+// which of its fields may change
+func (s *Scope) AccessAssignEval(a AccessAssign) (any, error) {
+	v, err := s.evaluate(a.Value)
+	if err != nil {
+		return nil, err
+	}
+	return s.SetField(a, v)
+}
+
+// SetField does the rest of `p.name = value` once the value is evaluated
+func (s *Scope) SetField(a AccessAssign, v any) (any, error) {
+	if err := s.mutable(a.Target.Left); err != nil {
+		return nil, err
+	}
+
+	instance, name, err := s.instance(a.Target)
+	if err != nil || instance == nil {
+		return nil, err
+	}
+	if _, isCall := a.Target.Right.(Call); isCall {
+		return nil, e.Error(name.Line, name.Column, name.Lexeme, e.RUNTIME, "cannot assign to a method call")
+	}
+
+	slot, err := instance.member(name, a.Target.Inside, true)
+	if err != nil {
+		return nil, err
+	}
+
+	return instance.Fields.Update(name, 0, slot, a.Operator, v)
+}
+
+// instance evaluates the left side of an access and finds the member name on the right,
+// there is no instance when a `?.` found nil
+func (s *Scope) instance(a Access) (*Instance, lexer.Token, error) {
+	left, name, skipped, err := s.accessed(a)
+	if err != nil || skipped {
+		return nil, name, err
+	}
+
+	instance, ok := left.(*Instance)
+	if !ok {
+		return nil, name, e.Error(name.Line, name.Column, name.Lexeme, e.RUNTIME, fmt.Sprintf("cannot access %s of %v", name.Lexeme, left))
+	}
+	return instance, name, nil
+}
+
+// Member reads the member of an access whose left side is already evaluated, a method is given as
+// it is, calling it is left to who asked. skipped tells a `?.` found nil, so the whole chain gives nil
+func (s *Scope) Member(a Access, left any) (any, bool, error) {
+	left, name, skipped, err := s.reached(a, left)
+	if err != nil || skipped {
+		return nil, skipped, err
+	}
+	if x, ok := left.(Err); ok {
+		if _, isCall := a.Right.(Call); isCall {
+			return nil, false, e.Error(name.Line, name.Column, name.Lexeme, e.RUNTIME, "err has no methods")
+		}
+		return x.member(name), false, nil
+	}
+	instance, ok := left.(*Instance)
+	if !ok {
+		return nil, false, e.Error(name.Line, name.Column, name.Lexeme, e.RUNTIME, fmt.Sprintf("cannot access %s of %v", name.Lexeme, left))
+	}
+
+	slot, err := instance.member(name, a.Inside, false)
+	if err != nil {
+		return nil, false, err
+	}
+	_, v, _, err := instance.Fields.Get(name, 0, slot)
+	return v, false, err
+}
+
+// Name is the name on the right of an access, the callee of `p.greet()` included
+func (a Access) Name() lexer.Token {
+	switch r := a.Right.(type) {
+	case Identifier:
+		return r.Name
+	case Call:
+		if i, ok := r.Callee.(Identifier); ok {
+			return i.Name
+		}
+	}
+	return lexer.Token{}
+}
+
+// accessed evaluates the left side of an access and finds the name of the member
+func (s *Scope) accessed(a Access) (any, lexer.Token, bool, error) {
+	left, skipped, err := s.link(a.Left)
+	if err != nil || skipped {
+		return nil, lexer.Token{}, skipped, err
+	}
+	return s.reached(a, left)
+}
+
+// reached finds the name of the member on the left side of an access. On nil, or a pointer to nil,
+// `?.` skips the rest of the chain and `!.` fails
+func (s *Scope) reached(a Access, left any) (any, lexer.Token, bool, error) {
+	var err error
+	name := a.Name()
+	op := a.Operator
+	if name.Lexeme == "" {
+		return nil, name, false, e.Error(op.Line, op.Column, op.Lexeme, e.RUNTIME, fmt.Sprintf("expect a member name after %s, found: %v", op.Lexeme, a.Right))
+	}
+
+	// a pointer is followed to what it points to, `p.name` is `(*p).name`
+	if p, ok := left.(Address); ok {
+		if left, err = p.load(op); err != nil {
+			return nil, name, false, err
+		}
+	}
+
+	if left == nil {
+		switch op.Type {
+		case lexer.CHECK_NAV:
+			return nil, name, true, nil
+		case lexer.BANG_NAV:
+			return nil, name, false, e.Error(op.Line, op.Column, op.Lexeme, e.RUNTIME, fmt.Sprintf("expect a value before !.%s, found nil", name.Lexeme))
+		}
+	}
+	return left, name, false, nil
+}
+
+// ElvisEval gives the left side unless it is nil, then the right side or the zero of the declared type
+func (s *Scope) ElvisEval(x Elvis) (any, error) {
+	v, err := s.evaluate(x.Left)
+	if err != nil || v != nil {
+		return v, err
+	}
+	if !x.ReturnZero {
+		return s.evaluate(x.Right)
+	}
+
+	op := x.Operator
+	if x.Type == UNKNOWN {
+		return nil, e.Error(op.Line, op.Column, op.Lexeme, e.RUNTIME, "?: found nil and the type of its left side is not declared, give it a value after ?:")
+	}
+	if v = zero(x.Type); v == nil {
+		return nil, e.Error(op.Line, op.Column, op.Lexeme, e.RUNTIME, fmt.Sprintf("?: found nil and %s has no zero value, give it a value after ?:", typeToString(x.Type)))
+	}
+	return v, nil
+}
+
+func (s *Scope) values(exprs []Expr) ([]any, error) {
+	values := make([]any, len(exprs))
+	for i, x := range exprs {
+		v, err := s.evaluate(x)
+		if err != nil {
+			return nil, err
+		}
+		values[i] = v
+	}
+	return values, nil
+}
+
 func (s *Scope) IdentifierEval(i Identifier) (res any, err error) {
-	_, res, _, err = s.Get(i.Name)
+	_, res, _, err = s.Get(i.Name, i.Depth, i.Slot)
 	if err != nil {
 		return nil, err
 	}
 
 	return
 }
+
+func (s *Scope) MoveEval(m Move) (any, error) {
+	return s.Move(m.Variable.Name, m.Variable.Depth, m.Variable.Slot, m.Quote)
+}
+
+// A borrowed variable must hold a value, the call decides if it is changed or only read
+func (s *Scope) BorrowEval(b Borrow) (any, error) {
+	v := b.Variable
+	if _, _, _, err := s.Get(v.Name, v.Depth, v.Slot); err != nil {
+		return nil, err
+	}
+	return Reference{Scope: s.ancestor(v.Depth), Slot: v.Slot, Name: v.Name}, nil
+}
+
+// Matches tries the patterns of an arm in order, binding in s the names of the one that matches
+func (s *Scope) Matches(patterns []Expr, value any) (bool, error) {
+	for _, pattern := range patterns {
+		if matched, err := s.match(pattern, value); err != nil || matched {
+			return matched, err
+		}
+	}
+	return false, nil
+}
+
+// An arm runs in its own scope, where the bindings of its patterns live
+func (s *Scope) CaseEval(c Case) (any, error) {
+	value, err := s.evaluate(c.Subject)
+	if err != nil {
+		return nil, err
+	}
+
+	for _, arm := range c.Arms {
+		scope := &Scope{Values: make([]Variable, 0, arm.Slots), Parent: s}
+		defer scope.End()
+
+		matched, err := scope.Matches(arm.Patterns, value)
+		if err != nil {
+			return nil, err
+		}
+
+		if matched && arm.Guard != nil {
+			guard, err := scope.evaluate(arm.Guard)
+			if err != nil {
+				return nil, err
+			}
+			if matched, err = Truthy(guard); err != nil {
+				return nil, err
+			}
+		}
+
+		if matched {
+			return scope.evaluate(arm.Body)
+		}
+	}
+
+	if c.Else != nil {
+		return s.evaluate(c.Else)
+	}
+
+	return nil, nil
+}
+
+func (s *Scope) RangeEval(r Range) (Interval, error) {
+	from, to, err := s.operands(r.Left, r.Right)
+	if err != nil {
+		return Interval{}, err
+	}
+
+	var step any
+	if r.Step != nil {
+		if step, err = s.evaluate(r.Step); err != nil {
+			return Interval{}, err
+		}
+	}
+
+	return NewInterval(r.Operator, from, to, step, r.Exclusive)
+}
+
+func (s *Scope) MembershipEval(m Membership) (any, error) {
+	value, values, err := s.operands(m.Left, m.Right)
+	if err != nil {
+		return nil, err
+	}
+
+	switch v := values.(type) {
+	case Interval:
+		return v.Contains(value), nil
+	case Array, Tuple, Slice:
+		values, _ := snapshot(v)
+		for _, x := range values {
+			if same(value, x) {
+				return true, nil
+			}
+		}
+		return false, nil
+	case Map:
+		_, found := v.Get(value)
+		return found, nil
+	case string:
+		switch x := value.(type) {
+		case string:
+			return strings.Contains(v, x), nil
+		case rune:
+			return strings.ContainsRune(v, x), nil
+		}
+	}
+
+	op := m.Operator
+	return nil, e.Error(op.Line, op.Column, op.Lexeme, e.RUNTIME, fmt.Sprintf("cannot look for %v in %v", value, values))
+}
+
+// An index picks one element and a range picks a part, `xs[1..3]` has the elements 1, 2 and 3
+func (s *Scope) PositionAccessEval(p PositionAccess) (any, error) {
+	v, _, err := s.link(p)
+	return v, err
+}
+
+// Position reads an index or a range of positions of a collection, both already evaluated. What is
+// inside a collection that cannot change cannot change either
+func (s *Scope) Position(p PositionAccess, collection any, pos any) (any, error) {
+	v, err := s.position(p, collection, pos)
+	if frozen(collection) {
+		v = freeze(v)
+	}
+	return v, err
+}
+
+func (s *Scope) position(p PositionAccess, collection any, pos any) (any, error) {
+	s.inspect(collection)
+
+	if m, ok := collection.(Map); ok {
+		return s.key(m, p.Bracket, pos)
+	}
+
+	// a single element is read in place, copying them all would cost more than the read
+	indexes, isRange := pos.(Interval)
+	switch c := collection.(type) {
+	case Array:
+		if !isRange {
+			i, err := index(p.Bracket, pos, len(c.Values))
+			if err != nil {
+				return nil, err
+			}
+			return c.at(i), nil
+		}
+	case Slice:
+		if !isRange {
+			i, err := index(p.Bracket, pos, c.Len())
+			if err != nil {
+				return nil, err
+			}
+			return c.at(i), nil
+		}
+	}
+
+	values, ok := snapshot(collection)
+	if str, isString := collection.(string); isString {
+		for _, c := range str {
+			values = append(values, c)
+		}
+	} else if !ok {
+		b := p.Bracket
+		return nil, e.Error(b.Line, b.Column, b.Lexeme, e.RUNTIME, fmt.Sprintf("cannot index %v", collection))
+	}
+
+	if !isRange {
+		i, err := index(p.Bracket, pos, len(values))
+		if err != nil {
+			return nil, err
+		}
+		return values[i], nil
+	}
+
+	part := make([]any, 0, indexes.Len())
+	err := indexes.Each(func(_ any, pos any) (bool, error) {
+		i, err := index(p.Bracket, pos, len(values))
+		part = append(part, values[i])
+		return false, err
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	switch v := collection.(type) {
+	case Array:
+		return Array{Type: v.Type, Values: part}, nil
+	case Slice:
+		return NewSlice(v.Type, part), nil
+	case string:
+		str := ""
+		for _, c := range part {
+			str += string(c.(rune))
+		}
+		return str, nil
+	default:
+		return Tuple(part), nil
+	}
+}
+
+// A key that is not in the map gives nil
+func (s *Scope) key(m Map, at lexer.Token, k any) (any, error) {
+	if _, ok := hashable(k); !ok {
+		return nil, e.Error(at.Line, at.Column, at.Lexeme, e.RUNTIME, fmt.Sprintf("%v cannot be a map key", k))
+	}
+
+	v, _ := m.Get(k)
+	return v, nil
+}
+
+// The element is changed in place, so the variable holding the collection has to be mutable
+func (s *Scope) PositionAssignEval(a PositionAssign) (any, error) {
+	v, err := s.evaluate(a.Value)
+	if err != nil {
+		return nil, err
+	}
+	return s.SetPosition(a, v)
+}
+
+// SetPosition does the rest of `a[i] = v` once the value is evaluated
+func (s *Scope) SetPosition(a PositionAssign, v any) (any, error) {
+	if err := s.mutable(a.Target.Expression); err != nil {
+		return nil, err
+	}
+
+	collection, pos, err := s.operands(a.Target.Expression, a.Target.Pos)
+	if err != nil {
+		return nil, err
+	}
+
+	b := a.Target.Bracket
+	if frozen(collection) {
+		return nil, e.Error(b.Line, b.Column, b.Lexeme, e.RUNTIME, fmt.Sprintf("cannot assign to a position of %s, it is held by an immutable variable", spelled(a.Target.Expression)))
+	}
+	switch c := collection.(type) {
+	case Array:
+		i, err := index(b, pos, len(c.Values))
+		if err != nil {
+			return nil, err
+		}
+
+		if v, err = compound(a.Operator, c.at(i), v); err != nil {
+			return nil, err
+		}
+		if v, err = fit(b, c.Type, v); err != nil {
+			return nil, err
+		}
+		if !c.holds(v) {
+			return nil, e.Error(b.Line, b.Column, b.Lexeme, e.RUNTIME, fmt.Sprintf("array of %s cannot hold %s: %v", typeToString(c.Type), typeToString(getType(v)), v))
+		}
+
+		c.put(i, v)
+		return v, nil
+	case Slice:
+		i, err := index(b, pos, c.Len())
+		if err != nil {
+			return nil, err
+		}
+
+		if v, err = compound(a.Operator, c.at(i), v); err != nil {
+			return nil, err
+		}
+		if v, err = fit(b, c.Type, v); err != nil {
+			return nil, err
+		}
+		if !c.holds(v) {
+			return nil, e.Error(b.Line, b.Column, b.Lexeme, e.RUNTIME, fmt.Sprintf("slice of %s cannot hold %s: %v", typeToString(c.Type), typeToString(getType(v)), v))
+		}
+
+		c.put(i, v)
+		return v, nil
+	case Map:
+		// a new key has no old value, so only a plain `=` may insert it
+		old, err := s.key(c, b, pos)
+		if err != nil {
+			return nil, err
+		}
+
+		if v, err = compound(a.Operator, old, v); err != nil {
+			return nil, err
+		}
+		if pos, err = fit(b, c.Key, pos); err != nil {
+			return nil, err
+		}
+		if v, err = fit(b, c.Value, v); err != nil {
+			return nil, err
+		}
+		if !c.holds(pos, v) {
+			return nil, e.Error(b.Line, b.Column, b.Lexeme, e.RUNTIME, fmt.Sprintf("map of %s to %s cannot hold %v: %v", typeToString(c.Key), typeToString(c.Value), pos, v))
+		}
+
+		c.Set(pos, v)
+		return v, nil
+	default:
+		return nil, e.Error(b.Line, b.Column, b.Lexeme, e.RUNTIME, fmt.Sprintf("cannot assign to a position of %v", collection))
+	}
+}
+
+// mutable checks the variable at the root of `a[i][j]` and `p.x`, values that are not in a variable can
+// always change. Inside its methods `this` is never checked, the obj decides what may change there
+func (s *Scope) mutable(expr Expr) error {
+	switch x := expr.(type) {
+	case PositionAccess:
+		return s.mutable(x.Expression)
+	case Access:
+		return s.mutable(x.Left)
+	case Grouping:
+		return s.mutable(x.Expression)
+	case Identifier:
+		scope := s.ancestor(x.Depth)
+		if scope == nil || x.Name.Type == lexer.THIS {
+			return nil
+		}
+		if v, found := scope.variable(x.Slot); found && !v.Mutable {
+			n := x.Name
+			return e.Error(n.Line, n.Column, n.Lexeme, e.RUNTIME, "cannot assign because "+n.Lexeme+" is immutable")
+		}
+	}
+	return nil
+}
+
+// Positions without a value are filled with the zero of the type, `[int: 3][7]` is [7, 0, 0]
+func (s *Scope) ArrayLiteralEval(a ArrayLiteral) (any, error) {
+	size, err := s.evaluate(a.Size)
+	if err != nil {
+		return nil, err
+	}
+	values, err := s.values(a.Values)
+	if err != nil {
+		return nil, err
+	}
+	return a.Build(size, values)
+}
+
+// Build makes the array from its size and its values, already evaluated
+func (a ArrayLiteral) Build(size any, values []any) (any, error) {
+	t := a.Typing
+	n, ok := size.(int)
+	if !ok || n < 0 {
+		return nil, e.Error(t.Line, t.Column, t.Lexeme, e.RUNTIME, fmt.Sprintf("array size should be a positive int, found: %v", size))
+	}
+	if len(values) > n {
+		return nil, e.Error(t.Line, t.Column, t.Lexeme, e.RUNTIME, fmt.Sprintf("array of size %d cannot hold %d values", n, len(values)))
+	}
+
+	array := Array{Type: tokenToType(t), Values: make([]any, n)}
+	if t.Type == lexer.ANY {
+		array.Type = UNDEFINED
+	} else if array.Type == UNKNOWN {
+		return nil, e.Error(t.Line, t.Column, t.Lexeme, e.RUNTIME, fmt.Sprintf("arrays of %s are not supported", t.Lexeme))
+	}
+
+	for i, v := range values {
+		v, err := fit(t, array.Type, v)
+		if err != nil {
+			return nil, err
+		}
+		if !array.holds(v) {
+			return nil, e.Error(t.Line, t.Column, t.Lexeme, e.RUNTIME, fmt.Sprintf("array of %s cannot hold %s: %v", typeToString(array.Type), typeToString(getType(v)), v))
+		}
+		array.Values[i] = v
+	}
+
+	for i := len(values); i < n; i++ {
+		array.Values[i] = zero(array.Type)
+	}
+
+	return array, nil
+}
+
+// Without a type in the literal, the elements decide it, any when they differ
+func (s *Scope) SliceLiteralEval(x SliceLiteral) (any, error) {
+	values, err := s.values(x.Values)
+	if err != nil {
+		return nil, err
+	}
+	return x.Build(values)
+}
+
+// Build makes the slice from its values, already evaluated
+func (x SliceLiteral) Build(values []any) (any, error) {
+	for i, v := range values {
+		v, err := fit(x.Bracket, x.Type, v)
+		if err != nil {
+			return nil, err
+		}
+		values[i] = v
+	}
+
+	slice := NewSlice(x.Type, values)
+	if x.Type == UNKNOWN {
+		slice.Type = UNDEFINED
+		for i, v := range values {
+			if i == 0 {
+				slice.Type = getType(v)
+			} else if getType(v) != slice.Type {
+				slice.Type = UNDEFINED
+				break
+			}
+		}
+	}
+
+	for _, v := range values {
+		if !slice.holds(v) {
+			b := x.Bracket
+			return nil, e.Error(b.Line, b.Column, b.Lexeme, e.RUNTIME, fmt.Sprintf("slice of %s cannot hold %s: %v", typeToString(slice.Type), typeToString(getType(v)), v))
+		}
+	}
+
+	return slice, nil
+}
+
+func (s *Scope) MapLiteralEval(x MapLiteral) (any, error) {
+	entries := make([]any, 0, 2*len(x.Keys))
+	for i := range x.Keys {
+		k, v, err := s.operands(x.Keys[i], x.Values[i])
+		if err != nil {
+			return nil, err
+		}
+		entries = append(entries, k, v)
+	}
+	return x.Build(entries)
+}
+
+// Build makes the map from its keys and values already evaluated, each key followed by its value
+func (x MapLiteral) Build(entries []any) (any, error) {
+	m := NewMap(x.Key, x.Value)
+	b := x.Bar
+
+	for i := 0; i < len(entries); i += 2 {
+		k, v := entries[i], entries[i+1]
+		if _, ok := hashable(k); !ok {
+			return nil, e.Error(b.Line, b.Column, b.Lexeme, e.RUNTIME, fmt.Sprintf("%v cannot be a map key", k))
+		}
+		k, err := fit(b, m.Key, k)
+		if err != nil {
+			return nil, err
+		}
+		if v, err = fit(b, m.Value, v); err != nil {
+			return nil, err
+		}
+		if !m.holds(k, v) {
+			return nil, e.Error(b.Line, b.Column, b.Lexeme, e.RUNTIME, fmt.Sprintf("map of %s to %s cannot hold %v: %v", typeToString(m.Key), typeToString(m.Value), k, v))
+		}
+		m.Set(k, v)
+	}
+
+	return m, nil
+}
+
+// index checks a position against the length of what is being indexed
+func index(at lexer.Token, pos any, length int) (int, error) {
+	var i int
+	switch v := widen(pos).(type) {
+	case int:
+		i = v
+	case uint:
+		i = int(v)
+	default:
+		return 0, e.Error(at.Line, at.Column, at.Lexeme, e.RUNTIME, fmt.Sprintf("index should be an int or a range, found: %v", pos))
+	}
+
+	if i < 0 || i >= length {
+		return 0, e.Error(at.Line, at.Column, at.Lexeme, e.RUNTIME, fmt.Sprintf("index %d out of bounds for length %d", i, length))
+	}
+	return i, nil
+}
@@ -5,6 +5,7 @@ import (
 	"strings"
 
 	e "github.com/ToniLommez/Neon_Dream_Runner/pkg/errutils"
+	"github.com/ToniLommez/Neon_Dream_Runner/pkg/lexer"
 )
 
 func (s *Scope) IfStmt(i IfStmt) (any, error) {
@@ -36,49 +37,187 @@ func (s *Scope) LetEval(l LetStmt) (any, error) {
 		if err != nil {
 			return nil, err
 		}
+	}
+
+	return s.Declare(l, value)
+}
+
+func (s *Scope) DestructureEval(d DestructureStmt) (any, error) {
+	value, err := s.evaluate(d.Initializer)
+	if err != nil {
+		return nil, err
+	}
+
+	return s.Unpack(d, value)
+}
+
+// Unpack declares every variable of `let x, y = ...` from the values of a Tuple
+func (s *Scope) Unpack(d DestructureStmt, value any) (any, error) {
+	name := d.Lets[0].Name
+	values, ok := value.(Tuple)
+	if !ok || len(values) != len(d.Lets) {
+		return nil, e.Error(name.Line, name.Column, name.Lexeme, e.RUNTIME, fmt.Sprintf("expected %d values to unpack, found: %v", len(d.Lets), value))
+	}
+
+	for i, l := range d.Lets {
+		l.Initializer = Literal{values[i]}
+		if _, err := s.Declare(l, values[i]); err != nil {
+			return nil, err
+		}
+	}
+
+	return nil, nil
+}
+
+// Declare checks the value against the let statement before defining it
+func (s *Scope) Declare(l LetStmt, value any) (any, error) {
+	if l.Initializer != nil {
+		fit, ok := fitted(l.Type, value)
+		if !ok {
+			return nil, e.Error(l.Name.Line, l.Name.Column, l.Name.Lexeme, e.RUNTIME, fmt.Sprintf("%v does not fit in %s", value, typeToString(l.Type)))
+		}
+		value = fit
 
 		valueType := getType(value)
 		if valueType == UNKNOWN || valueType == UNDEFINED {
 			return nil, e.Error(l.Name.Line, 0, "", e.RUNTIME, fmt.Sprintf("let statement evaluate to unknown type: %v", value))
 		}
 
+		value = adopt(l.Elem, l.Key, value)
+		value, arranged := arrange(l.Type, l.Size, l.Parts, value)
+		conforms, lacking := conforms(l.Object, s.trait(l.Named), value)
 		if l.Type == UNDEFINED {
-			l.Type = valueType
-		} else if l.Type != valueType {
-			return nil, e.Error(l.Name.Line, 0, "", e.RUNTIME, fmt.Sprintf("let statement expected %s, found %s", typeToString(l.Type), typeToString(valueType)))
+			l.Type, l.Object = valueType, objectOf(value)
+		} else if valueType != NIL && (l.Type != valueType || !fits(l.Elem, l.Key, value) || !arranged || !conforms) {
+			return nil, e.Error(l.Name.Line, 0, "", e.RUNTIME, fmt.Sprintf("let statement expected %s, found %s%s", written(l.Type, l.Elem, l.Key, l.Object, l.Size, l.Parts), describeValue(value), lacks(lacking)))
+		}
+		if l.Elem == UNKNOWN {
+			l.Elem, l.Key = shape(value)
 		}
 
 		if valueType == NIL && !l.Nullable {
-			return nil, e.Error(l.Name.Line, 0, "", e.RUNTIME, "non nullable let statement received nil value")
+			return nil, e.Error(l.Name.Line, l.Name.Column, l.Name.Lexeme, e.RUNTIME, l.Name.Lexeme+" cannot be set to nil")
 		}
 	}
 
-	_, err = s.Define(l, value)
+	_, err := s.Define(l, value)
 	return nil, err
 }
 
+func (s *Scope) FnEval(f FnStmt) (any, error) {
+	return s.DeclareFunction(Function{Declaration: f, Closure: s})
+}
+
+func (s *Scope) DeclareFunction(f Function) (any, error) {
+	d := f.Declaration
+	l := LetStmt{Name: d.Name, Type: FUNCTION, Initializer: d.Body, Slot: d.Slot}
+	_, err := s.Define(l, f)
+	return nil, err
+}
+
+func (s *Scope) ObjEval(o ObjStmt) (any, error) {
+	l := LetStmt{Name: o.Name, Type: UNDEFINED, Initializer: Literal{}, Slot: o.Slot}
+	_, err := s.Define(l, Object{Declaration: o, Closure: s})
+	return nil, err
+}
+
+func (s *Scope) TraitEval(t TraitStmt) (any, error) {
+	l := LetStmt{Name: t.Name, Type: UNDEFINED, Initializer: Literal{}, Slot: t.Slot}
+	_, err := s.Define(l, Trait{Declaration: t})
+	return nil, err
+}
+
+func (s *Scope) WhenEval(w WhenStmt) (any, error) {
+	s.events().When(w.Handler.Name.Lexeme, Function{Declaration: w.Handler, Closure: s})
+	return nil, nil
+}
+
+func (s *Scope) TriggerEval(t TriggerStmt) (any, error) {
+	args, err := s.values(t.Args)
+	if err != nil {
+		return nil, err
+	}
+	return nil, s.Trigger(t.Event, args)
+}
+
+func (s *Scope) ReturnEval(r ReturnStmt) (any, error) {
+	var value any
+	var err error
+
+	if r.Value != nil {
+		if value, err = s.evaluate(r.Value); err != nil {
+			return nil, err
+		}
+	}
+
+	return nil, returnSignal{Keyword: r.Keyword, Value: value}
+}
+
 func (s *Scope) PutEval(p PutStmt) (any, error) {
 	expr, err := s.evaluate(p.Value)
 	if err != nil {
 		return nil, err
 	}
 
+	PutValue(expr)
+	return nil, nil
+}
+
+func PutValue(expr any) {
 	tmp := fmt.Sprintf("%v", expr)
+	if c, ok := expr.(rune); ok {
+		tmp = string(c)
+	}
 	color := "\033[38;2;150;240;240m"
 	reset := "\033[0m"
 	fmt.Printf("%s%v%s", color, strings.Replace(tmp, "\\n", "\n", -1), reset)
 
 	// TODO: remove this after implement printf
 	fmt.Printf("\n")
+}
 
-	return nil, nil
+// loopSignal is a break or continue travelling up as an error until the loop it aims at
+type loopSignal struct {
+	Keyword lexer.Token
+	Label   string
+}
+
+func (l loopSignal) Error() string {
+	return e.Error(l.Keyword.Line, l.Keyword.Column, l.Keyword.Lexeme, e.RUNTIME, l.Keyword.Lexeme+" outside a loop").Error()
+}
+
+// IsLoop tells if an error is actually a break or continue looking for its loop, the label it aims
+// at, empty for the innermost loop, and if it is a break
+func IsLoop(err error) (label string, stop bool, ok bool) {
+	signal, ok := err.(loopSignal)
+	return signal.Label, signal.Keyword.Type == lexer.BREAK, ok
+}
+
+func (s *Scope) BreakEval(b BreakStmt) (any, error) {
+	return nil, loopSignal{Keyword: b.Keyword, Label: b.Label}
+}
+
+func (s *Scope) ContinueEval(c ContinueStmt) (any, error) {
+	return nil, loopSignal{Keyword: c.Keyword, Label: c.Label}
+}
+
+// iteration runs the body of a loop once, stop tells the loop to end because of a break
+// and err is anything the loop does not handle itself
+func (s *Scope) iteration(label string, body Expr) (stop bool, err error) {
+	if _, err = s.evaluate(body); err == nil {
+		return false, nil
+	}
+
+	if signal, ok := err.(loopSignal); ok && (signal.Label == "" || signal.Label == label) {
+		return signal.Keyword.Type == lexer.BREAK, nil
+	}
+	return false, err
 }
 
 func (s *Scope) WhileEval(w WhileStmt) (any, error) {
 	var err error
 	var c any  // Raw condition
 	var b bool // Truthy(Raw condition)
-	var i int
 
 	for {
 		if c, err = s.evaluate(w.Condition); err != nil {
@@ -89,25 +228,181 @@ func (s *Scope) WhileEval(w WhileStmt) (any, error) {
 			return nil, err
 		}
 
-		if !b {
+		if b == w.Until {
 			break
 		}
 
-		if _, err = s.evaluate(w.Body); err != nil {
+		if stop, err := s.iteration(w.Label, w.Body); err != nil || stop {
 			return nil, err
 		}
 
-		i++
+		if w.Increment != nil {
+			if _, err = s.evaluate(w.Increment); err != nil {
+				return nil, err
+			}
+		}
 	}
 
 	return nil, nil
 }
 
+func (s *Scope) DoEval(d DoStmt) (any, error) {
+	for {
+		if stop, err := s.iteration(d.Label, d.Body); err != nil || stop {
+			return nil, err
+		}
+
+		c, err := s.evaluate(d.Condition)
+		if err != nil {
+			return nil, err
+		}
+
+		b, err := Truthy(c)
+		if err != nil {
+			return nil, err
+		}
+
+		if b == d.Until {
+			return nil, nil
+		}
+	}
+}
+
+func (s *Scope) LoopEval(l LoopStmt) (any, error) {
+	if l.Times == nil {
+		for {
+			if stop, err := s.iteration(l.Label, l.Body); err != nil || stop {
+				return nil, err
+			}
+		}
+	}
+
+	return nil, s.iterate(l.Keyword, l.Times, func(_ any, _ any) (bool, error) {
+		return s.iteration(l.Label, l.Body)
+	})
+}
+
+// Every iteration gets its own scope, so a closure created inside keeps its own loop variables
+func (s *Scope) ForInEval(f ForInStmt) (any, error) {
+	return nil, s.iterate(f.Keyword, f.Collection, func(index any, value any) (bool, error) {
+		scope := &Scope{Values: make([]Variable, 0, f.Slots), Parent: s}
+
+		values := []any{value}
+		if len(f.Vars) == 2 {
+			values = []any{index, value}
+		}
+
+		for i, v := range f.Vars {
+			v.Initializer = Literal{values[i]}
+			if _, err := scope.Declare(v, values[i]); err != nil {
+				return false, err
+			}
+		}
+
+		defer scope.End()
+		return scope.iteration(f.Label, f.Body)
+	})
+}
+
+// iterate calls each with the position and the value of every element of a collection until it asks to stop
+func (s *Scope) iterate(at lexer.Token, collection Expr, each func(index any, value any) (bool, error)) error {
+	value, err := s.evaluate(collection)
+	if err != nil {
+		return err
+	}
+	next, err := Iterate(at, value)
+	if err != nil {
+		return err
+	}
+
+	for {
+		index, v, ok, err := next()
+		if err != nil || !ok {
+			return err
+		}
+		if stop, err := each(index, v); err != nil || stop {
+			return err
+		}
+	}
+}
+
+// Cursor gives the position and the value of the next element of a collection, ok is false once there are no more
+type Cursor func() (index any, value any, ok bool, err error)
+
+// Iterate walks a collection one element at a time, a number n counts from 0 to n - 1, a map gives
+// its keys as positions and a channel gives what it receives until it is closed. Arrays, slices and
+// maps are walked as they were when the walk started
+func Iterate(at lexer.Token, collection any) (Cursor, error) {
+	n := 0
+	switch v := collection.(type) {
+	case Interval:
+		return func() (any, any, bool, error) {
+			if n >= v.Len() {
+				return nil, nil, false, nil
+			}
+			n++
+			return n - 1, v.At(n - 1), true, nil
+		}, nil
+	case int:
+		return func() (any, any, bool, error) {
+			if n >= v {
+				return nil, nil, false, nil
+			}
+			n++
+			return n - 1, n - 1, true, nil
+		}, nil
+	case Array, Tuple, Slice:
+		values, _ := snapshot(v)
+		return func() (any, any, bool, error) {
+			if n >= len(values) {
+				return nil, nil, false, nil
+			}
+			n++
+			return n - 1, values[n-1], true, nil
+		}, nil
+	case Map:
+		entries := v.copied()
+		return func() (any, any, bool, error) {
+			if n >= len(entries) {
+				return nil, nil, false, nil
+			}
+			n++
+			return entries[n-1].key, entries[n-1].value, true, nil
+		}, nil
+	case Channel:
+		if v.Direction == inbound {
+			return nil, e.Error(at.Line, at.Column, at.Lexeme, e.RUNTIME, "cannot receive from a channel that only takes values in")
+		}
+		return func() (any, any, bool, error) {
+			x, ok, err := v.receive(at)
+			if err != nil || !ok {
+				return nil, nil, false, err
+			}
+			n++
+			return n - 1, x, true, nil
+		}, nil
+	case string:
+		runes := []rune(v)
+		return func() (any, any, bool, error) {
+			if n >= len(runes) {
+				return nil, nil, false, nil
+			}
+			n++
+			return n - 1, runes[n-1], true, nil
+		}, nil
+	default:
+		return nil, e.Error(at.Line, at.Column, at.Lexeme, e.RUNTIME, fmt.Sprintf("cannot iterate over %v", collection))
+	}
+}
+
 func (s *Scope) ExprEval(e ExprStmt) (any, error) {
 	return s.evaluate(e.Expr)
 }
 
+// Every execution of a block gets its own scope, so a closure created inside
+// keeps the variables of that execution even after the block is finished
 func (s *Scope) BlockEval(b Block) (any, error) {
-	b.Scope.Parent = s
-	return b.Scope.Interpret()
+	scope := Scope{Statements: b.Scope.Statements, Values: make([]Variable, 0, b.Slots), Parent: s}
+	defer scope.End()
+	return scope.Interpret()
 }
@@ -6,10 +6,24 @@ import (
 	e "github.com/ToniLommez/Neon_Dream_Runner/pkg/errutils"
 )
 
+// Interpret runs the statements of the scope after defining the ones the Resolver hoisted,
+// so a function may be called above its declaration
 func (s *Scope) Interpret() (any, error) {
 	var v any
 	var err error
 	for _, stmt := range s.Statements {
+		if Hoisted(stmt) {
+			if _, err = s.evaluate(stmt); err != nil {
+				return nil, err
+			}
+		}
+	}
+
+	for _, stmt := range s.Statements {
+		if Hoisted(stmt) {
+			v = nil
+			continue
+		}
 		if v, err = s.evaluate(stmt); err != nil {
 			return nil, err
 		}
@@ -20,28 +34,75 @@ func (s *Scope) Interpret() (any, error) {
 	return v, nil
 }
 
+// Evaluate runs a single node with the tree-walker, other backends use it for what they do not cover
+func (s *Scope) Evaluate(instruction any) (any, error) {
+	return s.evaluate(instruction)
+}
+
 func (s *Scope) evaluate(instruction any) (any, error) {
 	switch i := instruction.(type) {
 	case LetStmt:
 		return s.LetEval(i)
+	case DestructureStmt:
+		return s.DestructureEval(i)
+	case FnStmt:
+		return s.FnEval(i)
+	case Send:
+		return s.SendEval(i)
+	case ChannelLiteral:
+		return s.ChannelLiteralEval(i)
+	case ObjStmt:
+		return s.ObjEval(i)
+	case TraitStmt:
+		return s.TraitEval(i)
+	case WhenStmt:
+		return s.WhenEval(i)
+	case TriggerStmt:
+		return s.TriggerEval(i)
+	case ErrorStmt:
+		return s.ErrorEval(i)
+	case SpawnStmt:
+		return s.SpawnEval(i)
+	case PulseStmt:
+		return s.PulseEval(i)
+	case ReactiveStmt:
+		return s.ReactiveEval(i)
+	case JoinPoint:
+		return s.JoinPointEval(i)
+	case ReturnStmt:
+		return s.ReturnEval(i)
 	case IfStmt:
 		return s.IfStmt(i)
 	case PutStmt:
 		return s.PutEval(i)
 	case WhileStmt:
 		return s.WhileEval(i)
+	case DoStmt:
+		return s.DoEval(i)
+	case LoopStmt:
+		return s.LoopEval(i)
+	case ForInStmt:
+		return s.ForInEval(i)
+	case BreakStmt:
+		return s.BreakEval(i)
+	case ContinueStmt:
+		return s.ContinueEval(i)
 	case ExprStmt:
 		return s.ExprEval(i)
 	case Sequence:
 		return s.SequenceEval(i)
 	case Assign:
 		return s.AssignEval(i)
+	case PositionAssign:
+		return s.PositionAssignEval(i)
 	case Pipeline:
 		return nil, nil
 	case Ternary:
 		return s.TernaryEval(i)
 	case Range:
-		return nil, nil
+		return s.RangeEval(i)
+	case Membership:
+		return s.MembershipEval(i)
 	case Logic:
 		return s.LogicEval(i)
 	case Equality:
@@ -61,27 +122,51 @@ func (s *Scope) evaluate(instruction any) (any, error) {
 	case Increment:
 		return nil, nil
 	case Pointer:
-		return nil, nil
+		return s.PointerEval(i)
+	case PointerAssign:
+		return s.PointerAssignEval(i)
 	case Unary:
 		return s.UnaryEval(i)
 	case Access:
-		return nil, nil
+		return s.AccessEval(i)
+	case AccessAssign:
+		return s.AccessAssignEval(i)
+	case ObjectLiteral:
+		return s.ObjectLiteralEval(i)
 	case PositionAccess:
-		return nil, nil
+		return s.PositionAccessEval(i)
 	case Elvis:
-		return nil, nil
+		return s.ElvisEval(i)
 	case Check:
-		return nil, nil
+		return s.CheckEval(i)
+	case ErrLiteral:
+		return s.ErrLiteralEval(i)
 	case Cast:
 		return s.CastEval(i)
+	case Lambda:
+		return Function{Declaration: i.Declaration, Closure: s}, nil
+	case Multiple:
+		return s.MultipleEval(i)
+	case Call:
+		return s.CallEval(i)
+	case Case:
+		return s.CaseEval(i)
 	case Identifier:
 		return s.IdentifierEval(i)
+	case Move:
+		return s.MoveEval(i)
+	case Borrow:
+		return s.BorrowEval(i)
 	case Literal:
 		return i.Value, nil
 	case Type:
 		return i, nil
 	case ArrayLiteral:
-		return nil, nil
+		return s.ArrayLiteralEval(i)
+	case SliceLiteral:
+		return s.SliceLiteralEval(i)
+	case MapLiteral:
+		return s.MapLiteralEval(i)
 	case Grouping:
 		return s.evaluate(i.Expression)
 	case Block:
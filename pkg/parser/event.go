@@ -0,0 +1,70 @@
+package parser
+
+import (
+	"sync"
+
+	l "github.com/ToniLommez/Neon_Dream_Runner/pkg/lexer"
+)
+
+// Listener is a handler written in Go, subscribed by the program that embeds Neon
+type Listener func(args []any) error
+
+// Events keeps the handlers of every event, each one is a Function or a Listener.
+// They run in the order they were registered: the `when` of the script as it reaches
+// them, the ones of an obj as its instances are built, and the listeners of the host
+type Events struct {
+	handlers map[string][]any
+	mu       sync.Mutex
+}
+
+// When subscribes a handler to an event, it runs after the ones already registered
+func (ev *Events) When(event string, handler any) {
+	ev.mu.Lock()
+	defer ev.mu.Unlock()
+	if ev.handlers == nil {
+		ev.handlers = make(map[string][]any)
+	}
+	ev.handlers[event] = append(ev.handlers[event], handler)
+}
+
+// Handlers lists what runs when the event is triggered, in order
+func (ev *Events) Handlers(event string) []any {
+	ev.mu.Lock()
+	defer ev.mu.Unlock()
+	return ev.handlers[event]
+}
+
+// root is the outermost scope, its registries are shared by everything that runs in it
+func (s *Scope) root() *Scope {
+	root := s
+	for root.Parent != nil {
+		root = root.Parent
+	}
+	return root
+}
+
+func (s *Scope) events() *Events {
+	root := s.root()
+	if root.Events == nil {
+		root.Events = &Events{}
+	}
+	return root.Events
+}
+
+// Trigger runs the handlers of an event one after another, stopping at the first error.
+// Handlers registered while it runs only hear the next trigger
+func (s *Scope) Trigger(event l.Token, args []any) error {
+	for _, h := range s.events().Handlers(event.Lexeme) {
+		var err error
+		switch f := h.(type) {
+		case Function:
+			_, err = s.call(f, args, event)
+		case Listener:
+			err = f(args)
+		}
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
@@ -13,10 +13,20 @@ type Sequence struct {
 	Right Expr
 }
 
+// Depth and Slot are filled by the Resolver
 type Assign struct {
 	Target   l.Token
 	Operator l.Token
 	Value    Expr
+	Depth    int
+	Slot     int
+}
+
+// PositionAssign is `a[i] = v`, and its compound forms like `a[i] += v`
+type PositionAssign struct {
+	Target   PositionAccess
+	Operator l.Token
+	Value    Expr
 }
 
 type Pipeline struct {
@@ -31,9 +41,20 @@ type Ternary struct {
 	False      Expr
 }
 
+// Step is nil when the range does not give one, `a..b..step`
 type Range struct {
-	Left  Expr
-	Right Expr
+	Left      Expr
+	Operator  l.Token
+	Right     Expr
+	Step      Expr
+	Exclusive bool
+}
+
+// Membership is `x in values`
+type Membership struct {
+	Left     Expr
+	Operator l.Token
+	Right    Expr
 }
 
 type Logic struct {
@@ -91,6 +112,7 @@ type Increment struct {
 	Position   bool
 }
 
+// Pointer is `&x`, the address of x, or `*p`, what p points to
 type Pointer struct {
 	Operator l.Token
 	Right    Expr
@@ -101,27 +123,81 @@ type Unary struct {
 	Right    Expr
 }
 
+// Inside is the obj whose body encloses the access, the private members of its instances are visible there
 type Access struct {
 	Left     Expr
 	Operator l.Token
 	Right    Expr
+	Inside   string
+}
+
+// AccessAssign is `p.name = value`
+type AccessAssign struct {
+	Target   Access
+	Operator l.Token
+	Value    Expr
 }
 
+// PointerAssign is `*p = value`, and its compound forms like `*p += value`
+type PointerAssign struct {
+	Target   Pointer
+	Operator l.Token
+	Value    Expr
+}
+
+// Send is `channel <! value`
+type Send struct {
+	Channel Expr
+	Arrow   l.Token
+	Value   Expr
+}
+
+// ChannelLiteral is `chan T` and `chan T: size`, Size is nil for a channel without a buffer
+type ChannelLiteral struct {
+	Keyword l.Token
+	Type    int
+	Size    Expr
+}
+
+// ObjectLiteral builds an instance, Names is empty when the values follow the order of the fields
+type ObjectLiteral struct {
+	Object Identifier
+	Brace  l.Token
+	Names  []l.Token
+	Values []Expr
+}
+
+// Pos is an index `xs[1]` or a range `xs[1..3]`
 type PositionAccess struct {
 	Expression Expr
+	Bracket    l.Token
 	Pos        Expr
 }
 
+// Elvis is `x ?: y`, or `x ?:` that gives the zero of Type, the type x is declared with
 type Elvis struct {
 	Left       Expr
+	Operator   l.Token
 	ReturnZero bool
 	Right      Expr
+	Type       int
 }
 
+// Check is `x?`, `x? => results` or `x? { handler }`, what runs when x fails.
+// HaveReturn tells if Right is returned, otherwise the Handler block gives the value of x
 type Check struct {
 	Left       Expr
+	Mark       l.Token
 	HaveReturn bool
 	Right      Expr
+	Handler    Expr
+}
+
+// ErrLiteral is `err{type: kind, msg: "message"}`, an error that is not raised yet
+type ErrLiteral struct {
+	Keyword l.Token
+	Kind    l.Token
+	Message Expr
 }
 
 type Cast struct {
@@ -130,8 +206,39 @@ type Cast struct {
 	TypeCast Expr
 }
 
+// Token is the callee name or the opening parenthesis, used to report call errors
+type Call struct {
+	Callee Expr
+	Token  l.Token
+	Args   []Expr
+}
+
+type Lambda struct {
+	Declaration FnStmt
+}
+
+// Multiple holds the values of `=> a, b` and `let x, y = a, b`
+type Multiple struct {
+	Values []Expr
+}
+
+// Move is `x'`, the value of x goes to where the expression is used and x cannot be read anymore
+type Move struct {
+	Variable Identifier
+	Quote    l.Token
+}
+
+// Borrow is `x!` in the arguments of a call, a parameter declared with ! changes x itself
+type Borrow struct {
+	Variable Identifier
+	Bang     l.Token
+}
+
+// Depth and Slot are filled by the Resolver
 type Identifier struct {
-	Name l.Token
+	Name  l.Token
+	Depth int
+	Slot  int
 }
 
 type ArrayLiteral struct {
@@ -140,6 +247,22 @@ type ArrayLiteral struct {
 	Values []Expr
 }
 
+// SliceLiteral is `[1, 2]` or `[int][1, 2]`, Type is UNKNOWN when the elements tell it
+type SliceLiteral struct {
+	Bracket l.Token
+	Type    int
+	Values  []Expr
+}
+
+// MapLiteral is `|string: int|` or `|string: int|{"a": 1}`
+type MapLiteral struct {
+	Bar    l.Token
+	Key    int
+	Value  int
+	Keys   []Expr
+	Values []Expr
+}
+
 type Literal struct {
 	Value interface{}
 }
@@ -151,3 +274,39 @@ type Type struct {
 type Grouping struct {
 	Expression Expr
 }
+
+// Case is an expression, it evaluates to the body of the first arm that matches the subject
+type Case struct {
+	Keyword l.Token
+	Subject Expr
+	Arms    []Arm
+	Else    Expr
+}
+
+// Patterns are the alternatives of `of 0 | 1`, Slots is the size of the scope holding the bindings of the arm
+type Arm struct {
+	Patterns []Expr
+	Guard    Expr
+	Body     Expr
+	Slots    int
+}
+
+// Any other expression used as a pattern is compared by value, and a Range by containment
+type TypePattern struct {
+	Name     l.Token
+	Type     int
+	Nullable bool
+}
+
+// ListPattern matches `[head, tail...]`, Rest is the Binding after the elements, if any
+type ListPattern struct {
+	Bracket  l.Token
+	Elements []Expr
+	Rest     Expr
+}
+
+// Binding names a value inside a pattern, `_` only matches it, Slot is filled by the Resolver
+type Binding struct {
+	Name l.Token
+	Slot int
+}
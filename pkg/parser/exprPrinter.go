@@ -41,6 +41,14 @@ func (x Sequence) String() string {
 	return fmt.Sprintf("%v ; %v", x.Left, x.Right)
 }
 
+func (x Move) String() string {
+	return fmt.Sprintf("%v'", x.Variable)
+}
+
+func (x Borrow) String() string {
+	return fmt.Sprintf("%v!", x.Variable)
+}
+
 func (x Identifier) String() string {
 	return fmt.Sprintf("%v", x.Name)
 }
@@ -49,6 +57,14 @@ func (x Assign) String() string {
 	return parenthesize(x.Operator.Lexeme, x.Target, x.Value)
 }
 
+func (x PositionAssign) String() string {
+	return fmt.Sprintf("(%v %v %v)", x.Target, x.Operator.Lexeme, x.Value)
+}
+
+func (x PointerAssign) String() string {
+	return fmt.Sprintf("(%v %v %v)", x.Target, x.Operator.Lexeme, x.Value)
+}
+
 func (x Pipeline) String() string {
 	return parenthesize(x.Operator.Lexeme, x.Left, x.Right)
 }
@@ -62,7 +78,18 @@ func (x Ternary) String() string {
 }
 
 func (x Range) String() string {
-	return parenthesize("..", x.Left, x.Right)
+	dots := ".."
+	if x.Exclusive {
+		dots = "..<"
+	}
+	if x.Step != nil {
+		return parenthesize(dots, x.Left, x.Right, x.Step)
+	}
+	return parenthesize(dots, x.Left, x.Right)
+}
+
+func (x Membership) String() string {
+	return parenthesize("in", x.Left, x.Right)
 }
 
 func (x Logic) String() string {
@@ -81,6 +108,35 @@ func (x Access) String() string {
 	return parenthesize(x.Operator.Lexeme, x.Left, x.Right)
 }
 
+func (x AccessAssign) String() string {
+	return fmt.Sprintf("(%v %v %v)", x.Target, x.Operator.Lexeme, x.Value)
+}
+
+func (x Send) String() string {
+	return parenthesize(x.Arrow.Lexeme, x.Channel, x.Value)
+}
+
+func (x ChannelLiteral) String() string {
+	if x.Size == nil {
+		return parenthesize("chan " + typeToString(x.Type))
+	}
+	return parenthesize("chan "+typeToString(x.Type), x.Size)
+}
+
+func (x ObjectLiteral) String() string {
+	str := "(" + x.Object.Name.Lexeme + "{"
+	for i, v := range x.Values {
+		if i > 0 {
+			str += ", "
+		}
+		if len(x.Names) > 0 {
+			str += x.Names[i].Lexeme + ": "
+		}
+		str += fmt.Sprintf("%v", v)
+	}
+	return str + "})"
+}
+
 func (x Power) String() string {
 	return parenthesize("**", x.Left, x.Right)
 }
@@ -112,15 +168,53 @@ func (x Pointer) String() string {
 func (x Check) String() string {
 	if x.HaveReturn {
 		return fmt.Sprintf("(%v) ? => %v", x.Left, x.Right)
+	} else if x.Handler != nil {
+		return fmt.Sprintf("(%v) ? %v", x.Left, x.Handler)
 	} else {
 		return fmt.Sprintf("(%v)?", x.Left)
 	}
 }
 
+func (x ErrLiteral) String() string {
+	return fmt.Sprintf("err{type: %s, msg: %v}", x.Kind.Lexeme, x.Message)
+}
+
 func (x ArrayLiteral) String() string {
 	return fmt.Sprintf("([%s: %v]%v)", x.Typing.Lexeme, x.Size, x.Values)
 }
 
+func (x SliceLiteral) String() string {
+	return fmt.Sprintf("([%s]%v)", typeToString(x.Type), x.Values)
+}
+
+func (x MapLiteral) String() string {
+	str := fmt.Sprintf("(|%s: %s|", typeToString(x.Key), typeToString(x.Value))
+	for i := range x.Keys {
+		str += fmt.Sprintf(" %v: %v", x.Keys[i], x.Values[i])
+	}
+	return str + ")"
+}
+
+func (x Call) String() string {
+	return fmt.Sprintf("(call %v %v)", x.Callee, x.Args)
+}
+
+func (x Lambda) String() string {
+	return fmt.Sprintf("(lambda %v => %v)", x.Declaration.Params, x.Declaration.Body)
+}
+
+func (x Multiple) String() string {
+	return fmt.Sprintf("%v", x.Values)
+}
+
+func (x Case) String() string {
+	str := fmt.Sprintf("(case %v", x.Subject)
+	for _, arm := range x.Arms {
+		str += fmt.Sprintf(" (of %v if %v => %v)", arm.Patterns, arm.Guard, arm.Body)
+	}
+	return str + fmt.Sprintf(" else %v)", x.Else)
+}
+
 func (x Literal) String() string {
 	return fmt.Sprintf("%v", x.Value)
 }
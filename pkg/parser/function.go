@@ -0,0 +1,220 @@
+package parser
+
+import (
+	"fmt"
+
+	e "github.com/ToniLommez/Neon_Dream_Runner/pkg/errutils"
+	l "github.com/ToniLommez/Neon_Dream_Runner/pkg/lexer"
+)
+
+// Closure is the scope where the function was declared, it stays alive as long as the function does
+// Compiled is left for other backends to attach their own code, the tree-walker ignores it
+type Function struct {
+	Declaration FnStmt
+	Closure     *Scope
+	Compiled    any
+}
+
+func (f Function) Arity() int {
+	return len(f.Declaration.Params)
+}
+
+func (f Function) String() string {
+	return fmt.Sprintf("<fn %s>", f.Declaration.Name.Lexeme)
+}
+
+// Tuple is the value of a function that returns more than one result
+type Tuple []any
+
+func (t Tuple) String() string {
+	str := "("
+	for i, v := range t {
+		if i > 0 {
+			str += ", "
+		}
+		str += fmt.Sprintf("%v", v)
+	}
+	return str + ")"
+}
+
+// Reference is the value of `x!`, the slot of x in the scope that has it
+type Reference struct {
+	Scope *Scope
+	Slot  int
+	Name  l.Token
+}
+
+func (r Reference) String() string {
+	return r.Name.Lexeme + "!"
+}
+
+func (r Reference) value() (any, error) {
+	_, v, _, err := r.Scope.Get(r.Name, 0, r.Slot)
+	return v, err
+}
+
+// returnSignal travels up as an error until the function call that owns it
+type returnSignal struct {
+	Keyword l.Token
+	Value   any
+}
+
+func (r returnSignal) Error() string {
+	return e.Error(r.Keyword.Line, r.Keyword.Column, r.Keyword.Lexeme, e.RUNTIME, "return outside a function").Error()
+}
+
+// IsReturn tells if an error is actually a `=>` leaving its function, and its value
+func IsReturn(err error) (any, bool) {
+	r, ok := err.(returnSignal)
+	return r.Value, ok
+}
+
+func (s *Scope) Call(f Function, args []any, at l.Token) (any, error) {
+	return s.call(f, args, at)
+}
+
+func (s *Scope) call(f Function, args []any, at l.Token) (any, error) {
+	env, err := f.Bind(s, args, at)
+	if err != nil {
+		return nil, err
+	}
+	defer env.End()
+	if err = s.Advise(l.BEFORE, f.Declaration.Name, "", env); err != nil {
+		return nil, err
+	}
+
+	var res any
+	if b, ok := f.Declaration.Body.(Block); ok {
+		env.Statements = b.Scope.Statements
+		res, err = env.Interpret()
+	} else {
+		res, err = env.evaluate(f.Declaration.Body)
+	}
+
+	if res, err = f.Settle(res, err); err != nil {
+		return nil, err
+	}
+	if err = s.Advise(l.AFTER, f.Declaration.Name, "", env); err != nil {
+		return nil, err
+	}
+
+	return f.Results(res, at)
+}
+
+// Bind checks the arguments and creates the scope where the body runs, which remembers the caller
+// for the stack of the errors raised inside. A parameter declared with ! is a mutable copy, unless
+// the call lends a mutable variable with x!
+func (f Function) Bind(caller *Scope, args []any, at l.Token) (*Scope, error) {
+	d := f.Declaration
+
+	if len(args) != f.Arity() {
+		return nil, e.Error(at.Line, at.Column, at.Lexeme, e.RUNTIME, fmt.Sprintf("%s expects %d arguments, found %d", d.Name.Lexeme, f.Arity(), len(args)))
+	}
+
+	env := &Scope{Values: make([]Variable, len(d.Params), d.Slots), Parent: f.Closure, Frame: &Frame{Function: d.Name, At: at, Caller: caller}}
+	if root := f.Closure; root != nil && root.Parent == nil && root.MainCalled != nil && d.Name.Lexeme == "main" {
+		root.MainCalled.Store(true)
+	}
+
+	for i, param := range d.Params {
+		arg := args[i]
+		ref, lent := arg.(Reference)
+		if lent {
+			v, err := ref.value()
+			if err != nil {
+				return nil, err
+			}
+			arg = v
+		}
+
+		fit, ok := fitted(param.Type, arg)
+		if !ok {
+			return nil, e.Error(at.Line, at.Column, at.Lexeme, e.RUNTIME, fmt.Sprintf("argument %s of %s does not fit %v in %s", param.Name.Lexeme, d.Name.Lexeme, arg, typeToString(param.Type)))
+		}
+		arg, arranged := arrange(param.Type, param.Size, param.Parts, adopt(param.Elem, param.Key, fit))
+		tp := getType(arg)
+		if arg == nil && !param.Nullable {
+			return nil, e.Error(at.Line, at.Column, at.Lexeme, e.RUNTIME, fmt.Sprintf("argument %s of %s cannot be nil", param.Name.Lexeme, d.Name.Lexeme))
+		}
+		trait := env.trait(param.Named)
+		conforms, lacking := conforms(param.Object, trait, arg)
+		if param.Type != UNDEFINED && arg != nil && (tp != param.Type || !fits(param.Elem, param.Key, arg) || !arranged || !conforms) {
+			return nil, e.Error(at.Line, at.Column, at.Lexeme, e.RUNTIME, fmt.Sprintf("argument %s of %s expects %s, found %s%s", param.Name.Lexeme, d.Name.Lexeme, written(param.Type, param.Elem, param.Key, param.Object, param.Size, param.Parts), describeValue(arg), lacks(lacking)))
+		}
+
+		if param.Mutable && !lent {
+			arg = thaw(arg)
+		}
+		elem, key := param.Elem, param.Key
+		if elem == UNKNOWN {
+			elem, key = shape(arg)
+		}
+		env.Values[i] = Variable{Name: param.Name.Lexeme, Value: arg, Type: tp, Elem: elem, Key: key, Object: param.Object, Parts: param.Parts, Size: param.Size, Trait: trait, TypeDefined: param.Type != UNDEFINED, Mutable: param.Mutable, Nullable: param.Nullable, Initialized: true}
+
+		// only a parameter declared with ! changes what was lent with x!, the others get a copy
+		if lent && param.Mutable {
+			if v, _ := ref.Scope.variable(ref.Slot); !v.Mutable {
+				n := ref.Name
+				return nil, e.Error(n.Line, n.Column, n.Lexeme, e.RUNTIME, fmt.Sprintf("%s is immutable, %s cannot change it through %s", n.Lexeme, d.Name.Lexeme, param.Name.Lexeme))
+			}
+			env.Values[i].Ref = &ref
+		}
+	}
+
+	return env, nil
+}
+
+// Settle turns what left the body into the result of the call: the value of a `=>`, or the results
+// of an error when the function has an err slot or a `?` returns it
+func (f Function) Settle(res any, err error) (any, error) {
+	switch r := err.(type) {
+	case returnSignal:
+		return r.Value, nil
+	case raiseSignal:
+		if r.Returning || f.errSlot() >= 0 {
+			return f.failed(r.Err), nil
+		}
+	}
+	return res, err
+}
+
+// Results checks what the body returned against the declared return types
+func (f Function) Results(res any, at l.Token) (any, error) {
+	d := f.Declaration
+
+	if len(d.Returns) > 1 {
+		t, ok := res.(Tuple)
+		if !ok || len(t) != len(d.Returns) {
+			return nil, e.Error(at.Line, at.Column, at.Lexeme, e.RUNTIME, fmt.Sprintf("%s should return %d values", d.Name.Lexeme, len(d.Returns)))
+		}
+		for i, v := range t {
+			fit, ok := fitted(d.Returns[i], v)
+			if !ok {
+				return nil, e.Error(at.Line, at.Column, at.Lexeme, e.RUNTIME, fmt.Sprintf("%s returns %v, which does not fit in %s", d.Name.Lexeme, v, typeToString(d.Returns[i])))
+			}
+			t[i] = fit
+			if err := checkReturn(d, d.Returns[i], t[i], at); err != nil {
+				return nil, err
+			}
+		}
+	} else if len(d.Returns) == 1 {
+		fit, ok := fitted(d.Returns[0], res)
+		if !ok {
+			return nil, e.Error(at.Line, at.Column, at.Lexeme, e.RUNTIME, fmt.Sprintf("%s returns %v, which does not fit in %s", d.Name.Lexeme, res, typeToString(d.Returns[0])))
+		}
+		res = fit
+		if err := checkReturn(d, d.Returns[0], res, at); err != nil {
+			return nil, err
+		}
+	}
+
+	return res, nil
+}
+
+func checkReturn(d FnStmt, expected int, value any, at l.Token) error {
+	// an err slot is nil when nothing failed
+	if expected != UNDEFINED && getType(value) != expected && !(expected == ERR && value == nil) {
+		return e.Error(at.Line, at.Column, at.Lexeme, e.RUNTIME, fmt.Sprintf("%s should return %s, found %s", d.Name.Lexeme, typeToString(expected), typeToString(getType(value))))
+	}
+	return nil
+}
@@ -0,0 +1,133 @@
+package parser
+
+import (
+	"fmt"
+
+	e "github.com/ToniLommez/Neon_Dream_Runner/pkg/errutils"
+	l "github.com/ToniLommez/Neon_Dream_Runner/pkg/lexer"
+)
+
+// Interval is the value of `a..b`, `a..<b` and `a..b..step`. It counts down when
+// From is after To, Step is how far apart the values are and Type tells if the
+// endpoints are INT, UINT or CHAR, which is also the type of every value in it
+type Interval struct {
+	From      int
+	To        int
+	Step      int
+	Exclusive bool
+	Type      int
+}
+
+// NewInterval checks the endpoints and the step of a range, step is nil when not given
+func NewInterval(at l.Token, from any, to any, step any, exclusive bool) (Interval, error) {
+	a, aType, ok := endpoint(from)
+	b, bType, ok2 := endpoint(to)
+	if !ok || !ok2 || aType != bType {
+		return Interval{}, e.Error(at.Line, at.Column, at.Lexeme, e.RUNTIME, fmt.Sprintf("range expects two int, uint or char, found: %v and %v", from, to))
+	}
+
+	i := Interval{From: a, To: b, Step: 1, Exclusive: exclusive, Type: aType}
+	if step != nil {
+		s, sType, ok := endpoint(step)
+		if !ok || sType == CHAR || s <= 0 {
+			return Interval{}, e.Error(at.Line, at.Column, at.Lexeme, e.RUNTIME, fmt.Sprintf("range step should be a positive number, found: %v", step))
+		}
+		i.Step = s
+	}
+
+	return i, nil
+}
+
+func endpoint(v any) (int, int, bool) {
+	switch x := v.(type) {
+	case int:
+		return x, INT, true
+	case uint:
+		return int(x), UINT, true
+	case rune:
+		return int(x), CHAR, true
+	default:
+		return 0, UNKNOWN, false
+	}
+}
+
+func (i Interval) direction() int {
+	if i.From > i.To {
+		return -1
+	}
+	return 1
+}
+
+// last is the final value the interval may reach, before taking the step into account
+func (i Interval) last() int {
+	if i.Exclusive {
+		return i.To - i.direction()
+	}
+	return i.To
+}
+
+func (i Interval) Len() int {
+	if i.Exclusive && i.From == i.To {
+		return 0
+	}
+
+	distance := (i.last() - i.From) * i.direction()
+	if distance < 0 {
+		return 0
+	}
+	return distance/i.Step + 1
+}
+
+// At returns the value in the position n, counting from 0
+func (i Interval) At(n int) any {
+	return i.value(i.From + n*i.Step*i.direction())
+}
+
+func (i Interval) value(n int) any {
+	switch i.Type {
+	case UINT:
+		return uint(n)
+	case CHAR:
+		return rune(n)
+	default:
+		return n
+	}
+}
+
+// Contains tells if v is one of the values of the interval, an int may be checked against an uint interval and vice versa
+func (i Interval) Contains(v any) bool {
+	n, tp, ok := endpoint(v)
+	if !ok || (tp == CHAR) != (i.Type == CHAR) || i.Len() == 0 {
+		return false
+	}
+
+	offset := (n - i.From) * i.direction()
+	return offset >= 0 && offset <= (i.last()-i.From)*i.direction() && offset%i.Step == 0
+}
+
+// Each calls f with the position and the value of every element until it asks to stop
+func (i Interval) Each(f func(index any, value any) (bool, error)) error {
+	for n := 0; n < i.Len(); n++ {
+		if stop, err := f(n, i.At(n)); err != nil || stop {
+			return err
+		}
+	}
+	return nil
+}
+
+func (i Interval) String() string {
+	dots := ".."
+	if i.Exclusive {
+		dots = "..<"
+	}
+
+	str := fmt.Sprintf("%v%s%v", i.value(i.From), dots, i.value(i.To))
+	if i.Type == CHAR {
+		str = fmt.Sprintf("%c%s%c", rune(i.From), dots, rune(i.To))
+	}
+
+	if i.Step != 1 {
+		str += fmt.Sprintf("..%d", i.Step)
+	}
+	return str
+}
@@ -0,0 +1,117 @@
+package parser
+
+import "fmt"
+
+// Map keeps its entries in the order they were inserted, so printing and iterating
+// always follow the same order. Key and Value are the types of the entries, any when
+// UNDEFINED, and copies of a map share its entries. ReadOnly is set on a map read from
+// an immutable variable, nothing changes through it
+type Map struct {
+	Key      int
+	Value    int
+	ReadOnly bool
+	entries  *[]entry
+	index    map[any]int // position of every key in entries
+}
+
+type entry struct {
+	key   any
+	value any
+}
+
+// tupleKey is how a tuple is stored as a key, since Go cannot hash it
+type tupleKey string
+
+func NewMap(key int, value int) Map {
+	return Map{Key: key, Value: value, entries: &[]entry{}, index: make(map[any]int)}
+}
+
+// hashable turns a key into something Go can hash, collections other than tuples cannot be keys
+func hashable(k any) (any, bool) {
+	switch v := k.(type) {
+	case bool, rune, int, uint, float64, string, Int8, Int16, Int32, Uint8, Uint16, Uint32, Float32:
+		return k, true
+	case Tuple:
+		for _, x := range v {
+			if _, ok := hashable(x); !ok {
+				return nil, false
+			}
+		}
+		return tupleKey(fmt.Sprintf("%#v", []any(v))), true
+	default:
+		return nil, false
+	}
+}
+
+// holds tells if a key and a value may be stored in the map
+func (m Map) holds(k any, v any) bool {
+	return (m.Key == UNDEFINED || getType(k) == m.Key) && (m.Value == UNDEFINED || getType(v) == m.Value)
+}
+
+func (m Map) Len() int {
+	defer viewing()()
+	return len(*m.entries)
+}
+
+func (m Map) Get(k any) (any, bool) {
+	k, _ = fitted(m.Key, k)
+	h, _ := hashable(k)
+	defer viewing()()
+	i, found := m.index[h]
+	if !found {
+		return nil, false
+	}
+	return (*m.entries)[i].value, true
+}
+
+// Set inserts or replaces an entry, the key should already be hashable
+func (m Map) Set(k any, v any) {
+	k, _ = fitted(m.Key, k)
+	h, _ := hashable(k)
+	defer touched(m)
+	defer changing()()
+	if i, found := m.index[h]; found {
+		(*m.entries)[i].value = v
+		return
+	}
+
+	m.index[h] = len(*m.entries)
+	*m.entries = append(*m.entries, entry{key: k, value: v})
+}
+
+// Delete removes an entry and tells if it was there
+func (m Map) Delete(k any) bool {
+	k, _ = fitted(m.Key, k)
+	h, _ := hashable(k)
+	defer touched(m)
+	defer changing()()
+	i, found := m.index[h]
+	if !found {
+		return false
+	}
+
+	*m.entries = append((*m.entries)[:i], (*m.entries)[i+1:]...)
+	delete(m.index, h)
+	for j := i; j < len(*m.entries); j++ {
+		h, _ := hashable((*m.entries)[j].key)
+		m.index[h] = j
+	}
+	return true
+}
+
+// copied gives the entries as they are now, to go through them without holding shared
+func (m Map) copied() []entry {
+	defer viewing()()
+	return append([]entry(nil), *m.entries...)
+}
+
+func (m Map) String() string {
+	str := "{"
+	for i, x := range m.copied() {
+		if i > 0 {
+			str += ", "
+		}
+		str += fmt.Sprintf("%v: %v", x.key, x.value)
+	}
+	return str + "}"
+}
@@ -0,0 +1,396 @@
+package parser
+
+import (
+	"fmt"
+	"math"
+	"math/bits"
+
+	e "github.com/ToniLommez/Neon_Dream_Runner/pkg/errutils"
+	"github.com/ToniLommez/Neon_Dream_Runner/pkg/lexer"
+)
+
+// The sized numbers. int, uint and float are already i64, u64 and f64, and byte is u8
+type (
+	Int8    int8
+	Int16   int16
+	Int32   int32
+	Uint8   uint8
+	Uint16  uint16
+	Uint32  uint32
+	Float32 float32
+)
+
+func isSized(t int) bool {
+	switch t {
+	case I8, I16, I32, U8, U16, U32, F32:
+		return true
+	}
+	return false
+}
+
+// family is the default type a sized type computes with: int, uint or float
+func family(t int) int {
+	switch t {
+	case I8, I16, I32, INT:
+		return INT
+	case U8, U16, U32, UINT:
+		return UINT
+	case F32, FLOAT:
+		return FLOAT
+	}
+	return UNKNOWN
+}
+
+func width(t int) int {
+	switch t {
+	case I8, U8:
+		return 8
+	case I16, U16:
+		return 16
+	case I32, U32, F32:
+		return 32
+	}
+	return 64
+}
+
+// widen gives a sized number as its family, the other values are left as they are
+func widen(v any) any {
+	switch x := v.(type) {
+	case Int8:
+		return int(x)
+	case Int16:
+		return int(x)
+	case Int32:
+		return int(x)
+	case Uint8:
+		return uint(x)
+	case Uint16:
+		return uint(x)
+	case Uint32:
+		return uint(x)
+	case Float32:
+		return float64(x)
+	}
+	return v
+}
+
+// convert turns a bool, a char or a number of the default types into the default type t
+func convert(v any, t int) any {
+	switch x := v.(type) {
+	case bool:
+		return convert(boolToInt(x), t)
+	case rune:
+		return convert(int(x), t)
+	case int:
+		switch t {
+		case UINT:
+			return uint(x)
+		case FLOAT:
+			return float64(x)
+		}
+	case uint:
+		switch t {
+		case INT:
+			return int(x)
+		case FLOAT:
+			return float64(x)
+		}
+	case float64:
+		switch t {
+		case INT:
+			return int(x)
+		case UINT:
+			return uint(x)
+		}
+	}
+	return v
+}
+
+// narrow gives v, an int, uint or float, as the sized type t. ok is false when it does not fit
+func narrow(v any, t int) (any, bool) {
+	if t == F32 {
+		f, isFloat := v.(float64)
+		fits := isFloat && (math.Abs(f) <= math.MaxFloat32 || math.IsInf(f, 0) || math.IsNaN(f))
+		return Float32(f), fits
+	}
+
+	var n int64
+	var u uint64
+	switch x := v.(type) {
+	case int:
+		n, u = int64(x), uint64(x)
+		if x < 0 && family(t) == UINT {
+			return v, false
+		}
+	case uint:
+		n, u = int64(x), uint64(x)
+		if x > math.MaxInt64 && family(t) == INT {
+			return v, false
+		}
+	default:
+		return v, false
+	}
+
+	if family(t) == INT {
+		limit := int64(1) << (width(t) - 1)
+		return wrap(n, t), n >= -limit && n < limit
+	}
+	return wrap(int64(u), t), u < uint64(1)<<width(t)
+}
+
+// wrap keeps the bits of n that fit in the sized type t, the way a cast does
+func wrap(n int64, t int) any {
+	switch t {
+	case I8:
+		return Int8(n)
+	case I16:
+		return Int16(n)
+	case I32:
+		return Int32(n)
+	case U8:
+		return Uint8(n)
+	case U16:
+		return Uint16(n)
+	case U32:
+		return Uint32(n)
+	case F32:
+		return Float32(n)
+	}
+	return n
+}
+
+// fitted gives a number of the default types as the sized type t, so `let x: u8 = 200` takes the literal
+// as an u8. ok is false when it does not fit, any other value is left to the type checks
+func fitted(t int, v any) (any, bool) {
+	if !isSized(t) {
+		return v, true
+	}
+	switch getType(v) {
+	case INT, UINT:
+		if t != F32 {
+			return narrow(v, t)
+		}
+	case FLOAT:
+		if t == F32 {
+			return narrow(v, t)
+		}
+	}
+	return v, true
+}
+
+// fit is fitted where a value is stored, a number that does not fit in t is an error
+func fit(at lexer.Token, t int, v any) (any, error) {
+	fit, ok := fitted(t, v)
+	if !ok {
+		return nil, e.Error(at.Line, at.Column, at.Lexeme, e.RUNTIME, fmt.Sprintf("%v does not fit in %s", v, typeToString(t)))
+	}
+	return fit, nil
+}
+
+// promote finds the type both sides of an operator are taken to when one is sized. The default types
+// are the types of the literals, so they take the sized type of the other side, and two sized types
+// take the wider one. Signed and unsigned of the same width only meet to be compared, as ints
+func promote(l any, r any, acceptUint bool) (any, any, int) {
+	lType, rType := getType(l), getType(r)
+	t := UNKNOWN
+
+	known, other := lType, rType
+	if !isSized(known) {
+		known, other = rType, lType
+	}
+
+	switch {
+	case lType == rType:
+		t = lType
+	case !isSized(other):
+		switch other {
+		case BOOL, INT, UINT:
+			t = known
+		case FLOAT:
+			t = FLOAT
+			if known == F32 {
+				t = F32
+			}
+		}
+	case lType == F32 || rType == F32:
+		t = F32
+	case family(lType) == family(rType):
+		t = lType
+		if width(rType) > width(lType) {
+			t = rType
+		}
+	default:
+		signed, unsigned := lType, rType
+		if family(signed) != INT {
+			signed, unsigned = rType, lType
+		}
+		if width(signed) > width(unsigned) {
+			t = signed
+		} else if acceptUint {
+			t = INT
+		}
+	}
+
+	if t == INT || t == FLOAT {
+		return convert(widen(l), t), convert(widen(r), t), t
+	}
+	return l, r, t
+}
+
+// sized runs an operator of the default types on operands promoted to the sized type t and narrows the
+// result back to t. A result out of the range of t overflows, except on the bitwise operators, which
+// drop the bits that do not fit
+func sized(op lexer.Token, t int, l any, r any, f func(lexer.Token, any, any) (any, error)) (any, error) {
+	operands := []any{l, r}
+	for i, v := range operands {
+		if v == nil {
+			continue
+		}
+		w := widen(v)
+		if _, ok := narrow(convert(w, family(t)), t); !ok {
+			return nil, e.Error(op.Line, op.Column, op.Lexeme, e.RUNTIME, fmt.Sprintf("%v does not fit in %s", v, typeToString(t)))
+		}
+		operands[i] = convert(w, family(t))
+	}
+
+	res, err := f(op, operands[0], operands[1])
+	if err != nil || getType(res) != family(t) {
+		return res, err
+	}
+
+	switch op.Type {
+	case lexer.AND_BITWISE, lexer.OR_BITWISE, lexer.XOR_BITWISE, lexer.NAND_BITWISE, lexer.NOR_BITWISE, lexer.XNOR_BITWISE, lexer.NOT_BITWISE, lexer.SHIFT_LEFT, lexer.SHIFT_RIGHT:
+		n, _ := convert(res, INT).(int)
+		return wrap(int64(n), t), nil
+	}
+
+	v, ok := narrow(res, t)
+	if !ok {
+		return nil, overflow(op, l, r, t)
+	}
+	return v, nil
+}
+
+// overflow is the error of an operator whose result is out of the range of t, r is nil for a unary one
+func overflow(op lexer.Token, l any, r any, t int) error {
+	if r == nil {
+		return e.Error(op.Line, op.Column, op.Lexeme, e.RUNTIME, fmt.Sprintf("%s(%v) overflows %s", op.Lexeme, l, typeToString(t)))
+	}
+	return e.Error(op.Line, op.Column, op.Lexeme, e.RUNTIME, fmt.Sprintf("%v %s %v overflows %s", l, op.Lexeme, r, typeToString(t)))
+}
+
+// IntOp overflows like the sized types do, ok is false when the result of the operator does not fit in an int
+func IntOp(op lexer.TokenType, a int, b int) (n int, ok bool) {
+	switch op {
+	case lexer.PLUS:
+		n = a + b
+		return n, (n > a) == (b > 0)
+	case lexer.MINUS:
+		n = a - b
+		return n, (n < a) == (b > 0)
+	case lexer.STAR:
+		hi, lo := bits.Mul64(uint64(abs(a)), uint64(abs(b)))
+		n = a * b
+		limit := uint64(math.MaxInt64)
+		if (a < 0) != (b < 0) {
+			limit++
+		}
+		return n, hi == 0 && lo <= limit
+	case lexer.SLASH:
+		return a / b, a != math.MinInt || b != -1
+	case lexer.POW:
+		n = 1
+		for ; b > 0; b >>= 1 {
+			if b&1 == 1 {
+				if n, ok = IntOp(lexer.STAR, n, a); !ok {
+					return n, false
+				}
+			}
+			if b > 1 {
+				if a, ok = IntOp(lexer.STAR, a, a); !ok {
+					return n, false
+				}
+			}
+		}
+		return n, true
+	}
+	return 0, false
+}
+
+// uintOp is IntOp for uints, ok is false when the result is negative or too big
+func uintOp(op lexer.TokenType, a uint, b uint) (n uint, ok bool) {
+	switch op {
+	case lexer.PLUS:
+		n = a + b
+		return n, n >= a
+	case lexer.MINUS:
+		return a - b, b <= a
+	case lexer.STAR:
+		hi, lo := bits.Mul64(uint64(a), uint64(b))
+		return uint(lo), hi == 0
+	case lexer.POW:
+		n = 1
+		for ; b > 0; b >>= 1 {
+			if b&1 == 1 {
+				if n, ok = uintOp(lexer.STAR, n, a); !ok {
+					return n, false
+				}
+			}
+			if b > 1 {
+				if a, ok = uintOp(lexer.STAR, a, a); !ok {
+					return n, false
+				}
+			}
+		}
+		return n, true
+	}
+	return 0, false
+}
+
+// abs gives the distance of n to 0, which for math.MinInt only fits in an uint
+func abs(n int) uint {
+	if n < 0 {
+		return uint(-n)
+	}
+	return uint(n)
+}
+
+// rotate turns the bits of a sized integer around its own width
+func rotate(op lexer.Token, v any, n uint) any {
+	k := int(n % uint(width(getType(v))))
+	if op.Type == lexer.ROUNDSHIFT_RIGHT {
+		k = -k
+	}
+
+	switch x := v.(type) {
+	case Int8:
+		return Int8(bits.RotateLeft8(uint8(x), k))
+	case Int16:
+		return Int16(bits.RotateLeft16(uint16(x), k))
+	case Int32:
+		return Int32(bits.RotateLeft32(uint32(x), k))
+	case Uint8:
+		return Uint8(bits.RotateLeft8(uint8(x), k))
+	case Uint16:
+		return Uint16(bits.RotateLeft16(uint16(x), k))
+	case Uint32:
+		return Uint32(bits.RotateLeft32(uint32(x), k))
+	}
+	return v
+}
+
+// cast converts a value to the sized type t, dropping the bits that do not fit
+func cast(op lexer.Token, v any, t int) (any, error) {
+	w := widen(v)
+	switch getType(w) {
+	case BOOL, CHAR, INT, UINT:
+		n, _ := convert(w, INT).(int)
+		return wrap(int64(n), t), nil
+	case FLOAT:
+		if t == F32 {
+			return Float32(w.(float64)), nil
+		}
+		return wrap(int64(w.(float64)), t), nil
+	}
+	return nil, e.Error(op.Line, op.Column, op.Lexeme, e.RUNTIME, fmt.Sprintf("cannot convert %s to %s", describeValue(v), typeToString(t)))
+}
@@ -0,0 +1,164 @@
+package parser
+
+import (
+	"fmt"
+
+	e "github.com/ToniLommez/Neon_Dream_Runner/pkg/errutils"
+	l "github.com/ToniLommez/Neon_Dream_Runner/pkg/lexer"
+)
+
+// Object is the value of an `obj` declaration, Closure is the scope where it was declared
+type Object struct {
+	Declaration ObjStmt
+	Closure     *Scope
+}
+
+func (o Object) String() string {
+	return fmt.Sprintf("<obj %s>", o.Declaration.Name.Lexeme)
+}
+
+// Instance is built from an Object, its methods run inside Fields, the scope that
+// holds `this` in the slot 0, then the fields and the methods in the slots of the Resolver
+type Instance struct {
+	Object Object
+	Fields *Scope
+}
+
+func (i *Instance) Name() string {
+	return i.Object.Declaration.Name.Lexeme
+}
+
+// New builds an instance, names is empty when the values follow the order of the fields.
+// Fields without a value take their default, or the zero of their type, and the `when` of the obj
+// start listening to their events once the instance is complete
+func (o Object) New(at l.Token, names []l.Token, values []any) (*Instance, error) {
+	d := o.Declaration
+	scope := &Scope{Values: make([]Variable, 0, d.Slots), Parent: o.Closure}
+	instance := &Instance{Object: o, Fields: scope}
+
+	this := l.Token{Type: l.THIS, Lexeme: "this", Line: d.Name.Line, Column: d.Name.Column}
+	scope.Define(LetStmt{Name: this, Type: OBJECT, Object: d.Name.Lexeme, Initializer: Literal{instance}, Slot: 0}, instance)
+
+	for _, m := range d.Methods {
+		if _, err := scope.DeclareFunction(Function{Declaration: m, Closure: scope}); err != nil {
+			return nil, err
+		}
+	}
+
+	given, err := o.arguments(at, names, values)
+	if err != nil {
+		return nil, err
+	}
+
+	for _, f := range d.Fields {
+		v, found := given[f.Name.Lexeme]
+		if !found && f.Initializer != nil {
+			if v, err = scope.evaluate(f.Initializer); err != nil {
+				return nil, err
+			}
+		} else if !found {
+			v = zero(f.Type)
+			if v == nil && !f.Nullable {
+				return nil, e.Error(at.Line, at.Column, at.Lexeme, e.RUNTIME, fmt.Sprintf("%s needs a value for the field %s", d.Name.Lexeme, f.Name.Lexeme))
+			}
+		}
+
+		fit, ok := fitted(f.Type, v)
+		if !ok {
+			return nil, e.Error(at.Line, at.Column, at.Lexeme, e.RUNTIME, fmt.Sprintf("field %s of %s does not fit %v in %s", f.Name.Lexeme, d.Name.Lexeme, v, typeToString(f.Type)))
+		}
+		v, arranged := arrange(f.Type, f.Size, f.Parts, fit)
+
+		conforms, lacking := conforms(f.Object, scope.trait(f.Named), v)
+		if f.Type != UNDEFINED && v != nil && (getType(v) != f.Type || !fits(f.Elem, f.Key, adopt(f.Elem, f.Key, v)) || !arranged || !conforms) {
+			return nil, e.Error(at.Line, at.Column, at.Lexeme, e.RUNTIME, fmt.Sprintf("field %s of %s expects %s, found %s%s", f.Name.Lexeme, d.Name.Lexeme, written(f.Type, f.Elem, f.Key, f.Object, f.Size, f.Parts), describeValue(v), lacks(lacking)))
+		}
+
+		f.Initializer = Literal{v}
+		if _, err := scope.Declare(f, v); err != nil {
+			return nil, err
+		}
+	}
+
+	for _, h := range d.Handlers {
+		scope.events().When(h.Handler.Name.Lexeme, Function{Declaration: h.Handler, Closure: scope})
+	}
+
+	return instance, nil
+}
+
+// arguments pairs the values of `Person{...}` with the fields they go to
+func (o Object) arguments(at l.Token, names []l.Token, values []any) (map[string]any, error) {
+	d := o.Declaration
+	given := make(map[string]any, len(values))
+
+	if len(names) == 0 {
+		if len(values) > len(d.Fields) {
+			return nil, e.Error(at.Line, at.Column, at.Lexeme, e.RUNTIME, fmt.Sprintf("%s has %d fields, found %d values", d.Name.Lexeme, len(d.Fields), len(values)))
+		}
+		for i, v := range values {
+			given[d.Fields[i].Name.Lexeme] = v
+		}
+		return given, nil
+	}
+
+	for i, n := range names {
+		if _, found := d.field(n.Lexeme); !found {
+			return nil, e.Error(n.Line, n.Column, n.Lexeme, e.RUNTIME, fmt.Sprintf("%s has no field %s", d.Name.Lexeme, n.Lexeme))
+		}
+		if _, repeated := given[n.Lexeme]; repeated {
+			return nil, e.Error(n.Line, n.Column, n.Lexeme, e.RUNTIME, fmt.Sprintf("field %s is given more than once", n.Lexeme))
+		}
+		given[n.Lexeme] = values[i]
+	}
+	return given, nil
+}
+
+// field finds the declaration of a field by its name
+func (o ObjStmt) field(name string) (LetStmt, bool) {
+	for _, f := range o.Fields {
+		if f.Name.Lexeme == name {
+			return f, true
+		}
+	}
+	return LetStmt{}, false
+}
+
+// member finds the slot of a field or method to read it, or to assign it when write is true.
+// Private members are only visible inside the obj, unless a `get` or `set` lets outside code use them
+func (i *Instance) member(name l.Token, inside string, write bool) (int, error) {
+	d := i.Object.Declaration
+	n := name.Lexeme
+	for slot, v := range i.Fields.variables() {
+		if slot == 0 || v.Name != n {
+			continue
+		}
+
+		if d.Public[n] || inside == d.Name.Lexeme {
+			return slot, nil
+		}
+		if write && d.Getters[n] && !d.Setters[n] {
+			return 0, e.Error(name.Line, name.Column, n, e.RUNTIME, fmt.Sprintf("cannot assign to %s of %s, it is read only", n, d.Name.Lexeme))
+		}
+		if write && !d.Setters[n] {
+			return 0, e.Error(name.Line, name.Column, n, e.RUNTIME, fmt.Sprintf("cannot assign to %s of %s, it is private", n, d.Name.Lexeme))
+		}
+		if !write && !d.Getters[n] {
+			return 0, e.Error(name.Line, name.Column, n, e.RUNTIME, fmt.Sprintf("%s is private to %s", n, d.Name.Lexeme))
+		}
+		return slot, nil
+	}
+	return 0, e.Error(name.Line, name.Column, name.Lexeme, e.RUNTIME, fmt.Sprintf("%s has no member %s", d.Name.Lexeme, name.Lexeme))
+}
+
+func (i *Instance) String() string {
+	str := i.Name() + "{"
+	values := i.Fields.variables()
+	for n, f := range i.Object.Declaration.Fields {
+		if n > 0 {
+			str += ", "
+		}
+		str += fmt.Sprintf("%s: %v", f.Name.Lexeme, values[f.Slot].Value)
+	}
+	return str + "}"
+}
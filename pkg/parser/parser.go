@@ -8,99 +8,975 @@ import (
 )
 
 type Parser struct {
-	Tokens  []l.Token
-	Current int
-	Depth   int
+	Tokens    []l.Token
+	Current   int
+	Depth     int
+	Functions int    // how many function bodies enclose the current token
+	object    string // the obj whose body encloses the current token
+	loops     []loopLabel
+}
+
+// loopLabel is a loop enclosing the current token, name is empty when it has no label
+type loopLabel struct {
+	name   string
+	breaks bool
 }
 
 func (p *Parser) Parse() ([]Stmt, error) {
 	stmt := make([]Stmt, 0)
 
-	for !p.isLastToken() && !p.isAtEnd() {
-		if p.peek().Type == l.NEW_LINE {
-			p.consume(l.NEW_LINE)
+	for !p.isLastToken() && !p.isAtEnd() {
+		if p.match(l.NEW_LINE) {
+			continue
+		}
+
+		s, err := p.declaration()
+		if err != nil {
+			return stmt, err
+		}
+
+		stmt = append(stmt, s)
+	}
+
+	return stmt, nil
+}
+
+func (p *Parser) declaration() (Stmt, error) {
+	if p.match(l.LET) {
+		s, err := p.letStatement()
+
+		if err != nil {
+			p.Synchronize()
+			return s, err
+		}
+
+		return s, err
+	} else if p.match(l.FN) {
+		s, err := p.fnStatement()
+
+		if err != nil {
+			p.Synchronize()
+			return s, err
+		}
+
+		return s, err
+	} else if p.match(l.OBJ) {
+		s, err := p.objStatement()
+
+		if err != nil {
+			p.Synchronize()
+			return s, err
+		}
+
+		return s, err
+	} else if p.match(l.TRAIT) {
+		s, err := p.traitStatement()
+
+		if err != nil {
+			p.Synchronize()
+			return s, err
+		}
+
+		return s, err
+	} else if p.match(l.WHEN) {
+		s, err := p.whenStatement()
+
+		if err != nil {
+			p.Synchronize()
+			return s, err
+		}
+
+		return s, err
+	}
+
+	return p.statement()
+}
+
+// objStatement → "obj" identifier "{" ( ( "pub"? ( field | fnDecl ) | whenDecl | ( "get" | "set" ) identifier ( "," identifier )* ) "\n" )* "}"
+func (p *Parser) objStatement() (Stmt, error) {
+	name, err := p.consume(l.IDENTIFIER)
+	if err != nil {
+		return nil, err
+	}
+	if _, err := p.consume(l.LEFT_BRACE); err != nil {
+		return nil, err
+	}
+
+	outer := p.object
+	p.object = name.Lexeme
+	defer func() { p.object = outer }()
+
+	o := ObjStmt{Name: name, Public: make(map[string]bool), Getters: make(map[string]bool), Setters: make(map[string]bool)}
+	var accessors []l.Token
+	p.Depth++
+	for {
+		if err := p.ensureNotUnterminated(); err != nil {
+			return nil, err
+		}
+		if p.match(l.RIGHT_BRACE) {
+			break
+		}
+
+		pub := p.match(l.PUB)
+		if _, next := p.peekN(1); !pub && p.isAccessor() && next.Type == l.IDENTIFIER {
+			kind := p.advance()
+			names, err := p.accessors()
+			if err != nil {
+				return nil, err
+			}
+			for _, n := range names {
+				if kind.Lexeme == "get" {
+					o.Getters[n.Lexeme] = true
+				} else {
+					o.Setters[n.Lexeme] = true
+				}
+			}
+			accessors = append(accessors, names...)
+		} else if !pub && p.match(l.WHEN) {
+			s, err := p.whenStatement()
+			if err != nil {
+				return nil, err
+			}
+			o.Handlers = append(o.Handlers, s.(WhenStmt))
+		} else if p.match(l.FN) {
+			s, err := p.fnStatement()
+			if err != nil {
+				return nil, err
+			}
+			f := s.(FnStmt)
+			o.Methods = append(o.Methods, f)
+			o.Public[f.Name.Lexeme] = pub
+		} else {
+			f, err := p.field()
+			if err != nil {
+				return nil, err
+			}
+			o.Fields = append(o.Fields, f)
+			o.Public[f.Name.Lexeme] = pub
+		}
+
+		if !p.match(l.NEW_LINE, l.SEMICOLON) && !p.check(l.RIGHT_BRACE) {
+			t := p.peek()
+			return nil, e.Error(t.Line, t.Column, t.Lexeme, e.PARSER, "expect new line after obj member")
+		}
+	}
+	p.Depth--
+
+	for _, n := range accessors {
+		if _, found := o.field(n.Lexeme); !found {
+			return nil, e.Error(n.Line, n.Column, n.Lexeme, e.PARSER, fmt.Sprintf("%s has no field %s", name.Lexeme, n.Lexeme))
+		}
+	}
+
+	return o, nil
+}
+
+// traitStatement → "trait" identifier "{" ( "fn" identifier ( "(" parameters? ")" )? ( "=>" type ( "," type )* )? "\n" )* "}"
+func (p *Parser) traitStatement() (Stmt, error) {
+	name, err := p.consume(l.IDENTIFIER)
+	if err != nil {
+		return nil, err
+	}
+	if _, err := p.consume(l.LEFT_BRACE); err != nil {
+		return nil, err
+	}
+
+	t := TraitStmt{Name: name}
+	p.Depth++
+	for {
+		if err := p.ensureNotUnterminated(); err != nil {
+			return nil, err
+		}
+		if p.match(l.RIGHT_BRACE) {
+			break
+		}
+
+		if _, err := p.consume(l.FN); err != nil {
+			return nil, err
+		}
+		method, err := p.consume(l.IDENTIFIER)
+		if err != nil {
+			return nil, err
+		}
+		f, err := p.signature(method)
+		if err != nil {
+			return nil, err
+		}
+		t.Methods = append(t.Methods, f)
+
+		if !p.match(l.NEW_LINE, l.SEMICOLON) && !p.check(l.RIGHT_BRACE) {
+			t := p.peek()
+			return nil, e.Error(t.Line, t.Column, t.Lexeme, e.PARSER, "expect new line after trait method")
+		}
+	}
+	p.Depth--
+
+	return t, nil
+}
+
+// whenStatement → "when" identifier ( "(" parameters? ")" )? ( "=" expression | block )
+func (p *Parser) whenStatement() (Stmt, error) {
+	keyword := p.previous()
+	event, err := p.consume(l.IDENTIFIER)
+	if err != nil {
+		return nil, err
+	}
+
+	f, err := p.function(event)
+	if err != nil {
+		return nil, err
+	}
+	return WhenStmt{Keyword: keyword, Handler: f}, nil
+}
+
+// isAccessor tells if the obj member is a `get` or `set` list, not a field with that name
+func (p *Parser) isAccessor() bool {
+	t := p.peek()
+	return t.Type == l.IDENTIFIER && (t.Lexeme == "get" || t.Lexeme == "set")
+}
+
+// accessors reads the field names of `get a, b` and `set a`
+func (p *Parser) accessors() ([]l.Token, error) {
+	names := make([]l.Token, 0)
+	for {
+		n, err := p.consume(l.IDENTIFIER)
+		if err != nil {
+			return nil, err
+		}
+		names = append(names, n)
+		if !p.match(l.COMMA) {
+			return names, nil
+		}
+	}
+}
+
+// field → identifier ( ":" type )? ( "=" expression )?, it needs at least one of them
+func (p *Parser) field() (LetStmt, error) {
+	name, err := p.consume(l.IDENTIFIER)
+	if err != nil {
+		return LetStmt{}, err
+	}
+
+	f := LetStmt{Name: name, Mutable: true, Type: UNDEFINED}
+	if p.match(l.COLON) {
+		a, err := p.typeAnnotation()
+		if err != nil {
+			return LetStmt{}, err
+		}
+		f.Type, f.Elem, f.Key, f.Object, f.Nullable, f.Parts, f.Size = a.Type, a.Elem, a.Key, a.Object, a.Nullable, a.Parts, a.Size
+	}
+
+	if p.match(l.ASSIGN) {
+		if f.Initializer, err = p.expression(); err != nil {
+			return LetStmt{}, err
+		}
+	} else if f.Type == UNDEFINED {
+		return LetStmt{}, e.Error(name.Line, name.Column, name.Lexeme, e.PARSER, "field needs a type or a default value")
+	}
+
+	return f, nil
+}
+
+func (p *Parser) letStatement() (Stmt, error) {
+	var mutable, nullable bool
+	var initializer Expr
+	var name l.Token
+	var err error
+
+	if p.match(l.BANG) {
+		mutable = true
+	}
+	if p.match(l.CHECK) {
+		nullable = true
+	}
+
+	name, err = p.consume(l.IDENTIFIER)
+	if err != nil {
+		return nil, err
+	}
+
+	// let f(x) = x + 3
+	if p.check(l.LEFT_PAREN) {
+		return p.function(name)
+	}
+
+	// let x, y = f 3 5
+	if p.check(l.COMMA) {
+		return p.destructure(name, mutable, nullable)
+	}
+
+	varType := annotation{Type: UNDEFINED}
+	if p.match(l.COLON) {
+		if varType, err = p.typeAnnotation(); err != nil {
+			return nil, err
+		}
+	}
+
+	if p.match(l.ASSIGN) {
+		initializer, err = p.expression()
+		if err != nil {
+			return initializer, err
+		}
+	} else if p.match(l.GO_IN) {
+		// let x <! ch is let x = <! ch
+		arrow := p.previous()
+		right, err := p.expression()
+		if err != nil {
+			return nil, err
+		}
+		initializer = Unary{arrow, right}
+	}
+
+	// Bad smell, but it's working... So, it's a problem for the future when it broke the whole thing
+	if t := p.peek().Type; t != l.RIGHT_BRACE && t != l.SEMICOLON {
+		if _, err := p.consume(l.NEW_LINE); err != nil {
+			t := p.peek()
+			return nil, e.Error(t.Line, t.Column, t.Lexeme, e.PARSER, "expect new line after let statement")
+		}
+	}
+
+	return LetStmt{Name: name, Mutable: mutable, Nullable: nullable || varType.Nullable, Type: varType.Type, Elem: varType.Elem, Key: varType.Key, Object: varType.Object, Parts: varType.Parts, Size: varType.Size, Initializer: initializer}, nil
+}
+
+func (p *Parser) destructure(first l.Token, mutable bool, nullable bool) (Stmt, error) {
+	lets := []LetStmt{{Name: first, Mutable: mutable, Nullable: nullable, Type: UNDEFINED}}
+
+	for p.match(l.COMMA) {
+		name, err := p.consume(l.IDENTIFIER)
+		if err != nil {
+			return nil, err
+		}
+		lets = append(lets, LetStmt{Name: name, Mutable: mutable, Nullable: nullable, Type: UNDEFINED})
+	}
+
+	if _, err := p.consume(l.ASSIGN); err != nil {
+		return nil, err
+	}
+
+	initializer, err := p.expression()
+	if err != nil {
+		return nil, err
+	}
+	if seq, ok := initializer.(Sequence); ok {
+		initializer = Multiple{Values: flatten(seq)}
+	}
+
+	if t := p.peek().Type; t != l.RIGHT_BRACE && t != l.SEMICOLON {
+		if _, err := p.consume(l.NEW_LINE); err != nil {
+			t := p.peek()
+			return nil, e.Error(t.Line, t.Column, t.Lexeme, e.PARSER, "expect new line after let statement")
+		}
+	}
+
+	return DestructureStmt{Lets: lets, Initializer: initializer}, nil
+}
+
+func (p *Parser) fnStatement() (Stmt, error) {
+	// fn! marks a function that mutates the object it belongs to
+	mutating := p.match(l.BANG)
+
+	name, err := p.consume(l.IDENTIFIER)
+	if err != nil {
+		return nil, err
+	}
+
+	f, err := p.function(name)
+	f.Mutating = mutating
+	return f, err
+}
+
+// function reads what comes after the name of `fn name` and `let name(...)`
+func (p *Parser) function(name l.Token) (FnStmt, error) {
+	var body Expr
+
+	f, err := p.signature(name)
+	if err != nil {
+		return FnStmt{}, err
+	}
+
+	// a function body starts outside of any loop
+	loops := p.loops
+	p.loops = nil
+	p.Functions++
+	if p.match(l.ASSIGN) {
+		body, err = p.expression()
+	} else if err = p.ensureNotUnterminated(); err == nil {
+		body, err = p.block(true)
+	}
+	p.Functions--
+	p.loops = loops
+	if err != nil {
+		return FnStmt{}, err
+	}
+
+	f.Body = multipleResults(body, f.Returns)
+	return f, nil
+}
+
+// signature reads the parameters and the return types that come after the name of a function
+func (p *Parser) signature(name l.Token) (FnStmt, error) {
+	f := FnStmt{Name: name}
+	var err error
+
+	if p.match(l.LEFT_PAREN) {
+		if f.Params, err = p.parameters(); err != nil {
+			return FnStmt{}, err
+		}
+	}
+
+	if p.match(l.RETURN) {
+		if f.Returns, err = p.returnTypes(); err != nil {
+			return FnStmt{}, err
+		}
+	}
+
+	return f, nil
+}
+
+func (p *Parser) returnTypes() ([]int, error) {
+	returns := make([]int, 0)
+	for {
+		t, err := p.typeAnnotation()
+		if err != nil {
+			return nil, err
+		}
+		returns = append(returns, t.Type)
+		if !p.match(l.COMMA) {
+			return returns, nil
+		}
+	}
+}
+
+// multipleResults turns a trailing `a, b` into a list of values when the function returns more than one
+func multipleResults(body Expr, returns []int) Expr {
+	if _, ok := body.(Block); len(returns) < 2 || !ok {
+		return body
+	}
+	return results(body)
+}
+
+// results turns `a, b`, alone or at the end of a block, into the values a function returns
+func results(x Expr) Expr {
+	switch v := x.(type) {
+	case Sequence:
+		return Multiple{Values: flatten(v)}
+	case Block:
+		if len(v.Scope.Statements) == 0 {
+			return v
+		}
+		last := len(v.Scope.Statements) - 1
+		if stmt, ok := v.Scope.Statements[last].(ExprStmt); ok {
+			if seq, ok := stmt.Expr.(Sequence); ok {
+				v.Scope.Statements[last] = ExprStmt{Expr: Multiple{Values: flatten(seq)}}
+			}
+		}
+		return v
+	default:
+		return x
+	}
+}
+
+// flatten unrolls the left associative `a, b, c` into its values
+func flatten(seq Sequence) []Expr {
+	if left, ok := seq.Left.(Sequence); ok {
+		return append(flatten(left), seq.Right)
+	}
+	return []Expr{seq.Left, seq.Right}
+}
+
+// parameters reads `name: type, ...)` after the opening parenthesis, the colon is optional
+func (p *Parser) parameters() ([]Param, error) {
+	params := make([]Param, 0)
+
+	if p.match(l.RIGHT_PAREN) {
+		return params, nil
+	}
+
+	for {
+		name, err := p.consume(l.IDENTIFIER)
+		if err != nil {
+			return nil, err
+		}
+
+		param := Param{Name: name, Type: UNDEFINED}
+		if p.match(l.COLON) || p.startsType() {
+			a, err := p.typeAnnotation()
+			if err != nil {
+				return nil, err
+			}
+			param.Type, param.Elem, param.Key, param.Object, param.Nullable, param.Parts, param.Size = a.Type, a.Elem, a.Key, a.Object, a.Nullable, a.Parts, a.Size
+		}
+		param.Mutable = p.match(l.BANG)
+
+		params = append(params, param)
+		if !p.match(l.COMMA) {
+			break
+		}
+	}
+
+	if _, err := p.consume(l.RIGHT_PAREN); err != nil {
+		return nil, err
+	}
+
+	return params, nil
+}
+
+// annotation is a written type, Elem and Key are the element and key types of `[int]`,
+// `[int: 3]` and `|string: int|`, UNKNOWN for every other type, and Object is the name of an obj.
+// Parts are the annotations of the elements of a tuple, of the element of a slice or an array and
+// of the key and the value of a map. Size is the one of an array
+type annotation struct {
+	Type     int
+	Elem     int
+	Key      int
+	Object   string
+	Nullable bool
+	Parts    []annotation
+	Size     int
+}
+
+// startsType tells if a type comes next, for the places where the colon before it is optional
+func (p *Parser) startsType() bool {
+	t := p.peek().Type
+	return t.IsType() || t == l.FN || t == l.LEFT_BRACKET || t == l.OR_BITWISE || t == l.IDENTIFIER || t == l.CHAN || t == l.STAR
+}
+
+// typeAnnotation reads a type followed by an optional `?`, `any` leaves the type unchecked
+func (p *Parser) typeAnnotation() (annotation, error) {
+	t := p.advance()
+
+	switch t.Type {
+	// only the fact that it is a function is checked, not its signature `fn(int) => int`
+	case l.FN:
+		if p.match(l.LEFT_PAREN) && !p.match(l.RIGHT_PAREN) {
+			if _, err := p.types(l.RIGHT_PAREN); err != nil {
+				return annotation{}, err
+			}
+		}
+		if p.match(l.RETURN) {
+			if _, err := p.typeAnnotation(); err != nil {
+				return annotation{}, err
+			}
+		}
+		return annotation{Type: FUNCTION, Nullable: p.match(l.CHECK)}, nil
+
+	// `[int]` is a slice and `[int: 3]` an array
+	case l.LEFT_BRACKET:
+		elem, err := p.typeAnnotation()
+		if err != nil {
+			return annotation{}, err
+		}
+
+		a := annotation{Type: SLICE, Elem: elem.Type, Parts: []annotation{elem}}
+		if p.match(l.COLON) {
+			size, err := p.expression()
+			if err != nil {
+				return annotation{}, err
+			}
+			literal, _ := size.(Literal)
+			n, ok := literal.Value.(int)
+			if !ok || n < 0 {
+				return annotation{}, e.Error(t.Line, t.Column, t.Lexeme, e.PARSER, "the size of an array type should be a number")
+			}
+			a.Type, a.Size = ARRAY, n
+		}
+		if _, err := p.consume(l.RIGHT_BRACKET); err != nil {
+			return annotation{}, err
+		}
+		a.Nullable = p.match(l.CHECK)
+		return a, nil
+
+	// `(int, string)` is a tuple of an int and a string
+	case l.LEFT_PAREN:
+		parts, err := p.types(l.RIGHT_PAREN)
+		if err != nil {
+			return annotation{}, err
+		}
+		return annotation{Type: TUPLE, Parts: parts, Nullable: p.match(l.CHECK)}, nil
+
+	case l.OR_BITWISE:
+		key, value, err := p.mapType()
+		if err != nil {
+			return annotation{}, err
+		}
+		return annotation{Type: MAP, Elem: value.Type, Key: key.Type, Parts: []annotation{key, value}, Nullable: p.match(l.CHECK)}, nil
+
+	// `*int` points to an int, `*int?` is a pointer that may be nil
+	case l.STAR:
+		elem, err := p.typeAnnotation()
+		if err != nil {
+			return annotation{}, err
+		}
+		return annotation{Type: POINTER, Elem: elem.Type, Object: elem.Object, Nullable: elem.Nullable}, nil
+
+	case l.IDENTIFIER:
+		if t.Lexeme == errName {
+			return annotation{Type: ERR, Nullable: p.match(l.CHECK)}, nil
+		}
+		return annotation{Type: OBJECT, Object: t.Lexeme, Nullable: p.match(l.CHECK)}, nil
+
+	// `chan <! int` only takes values in, `chan !> int` only gives them out and `chan <!> int` or `chan int` does both
+	case l.CHAN:
+		direction := bidirectional
+		if p.match(l.GO_IN) {
+			direction = inbound
+		} else if p.match(l.GO_OUT) {
+			direction = outbound
+		} else {
+			p.match(l.GO_BI)
+		}
+
+		elem, err := p.typeAnnotation()
+		if err != nil {
+			return annotation{}, err
+		}
+		return annotation{Type: CHANNEL, Elem: elem.Type, Key: direction, Nullable: p.match(l.CHECK)}, nil
+	}
+
+	if !t.Type.IsValidType() && t.Type != l.ANY {
+		return annotation{}, e.Error(t.Line, t.Column, t.Lexeme, e.PARSER, fmt.Sprintf("expect type, found: %v", t))
+	}
+
+	tp := tokenToType(t)
+	if t.Type == l.ANY {
+		tp = UNDEFINED
+	}
+
+	return annotation{Type: tp, Nullable: p.match(l.CHECK)}, nil
+}
+
+// types reads the annotations of `type, type...` up to the closing token
+func (p *Parser) types(closing l.TokenType) ([]annotation, error) {
+	types := make([]annotation, 0)
+	for {
+		a, err := p.typeAnnotation()
+		if err != nil {
+			return nil, err
+		}
+		types = append(types, a)
+		if !p.match(l.COMMA) {
+			break
+		}
+	}
+
+	if _, err := p.consume(closing); err != nil {
+		return nil, err
+	}
+	return types, nil
+}
+
+// mapType reads `key: value|` after the opening bar, `|(int, int): float|` has tuples as keys
+// and `|int: int, int|` tuples as values
+func (p *Parser) mapType() (annotation, annotation, error) {
+	key, err := p.typeAnnotation()
+	if err != nil {
+		return annotation{}, annotation{}, err
+	}
+
+	if _, err := p.consume(l.COLON); err != nil {
+		return annotation{}, annotation{}, err
+	}
+
+	values, err := p.types(l.OR_BITWISE)
+	if err != nil {
+		return annotation{}, annotation{}, err
+	}
+	if len(values) > 1 {
+		return key, annotation{Type: TUPLE, Parts: values}, nil
+	}
+	return key, values[0], nil
+}
+
+func (p *Parser) returnStatement() (Stmt, error) {
+	var value Expr
+	var err error
+
+	keyword := p.previous()
+	if p.Functions == 0 {
+		return nil, e.Error(keyword.Line, keyword.Column, keyword.Lexeme, e.PARSER, "cannot return outside a function")
+	}
+
+	if t := p.peek().Type; t != l.NEW_LINE && t != l.RIGHT_BRACE && t != l.SEMICOLON && t != l.EOF {
+		if value, err = p.expression(); err != nil {
+			return nil, err
+		}
+		if seq, ok := value.(Sequence); ok {
+			value = Multiple{Values: flatten(seq)}
+		}
+	}
+
+	if t := p.peek().Type; t != l.RIGHT_BRACE && t != l.SEMICOLON && t != l.EOF {
+		if _, err := p.consume(l.NEW_LINE); err != nil {
+			t := p.peek()
+			return nil, e.Error(t.Line, t.Column, t.Lexeme, e.PARSER, "expect new line after return")
+		}
+	}
+
+	return ReturnStmt{Keyword: keyword, Value: value}, nil
+}
+
+func (p *Parser) statement() (Stmt, error) {
+	label := ""
+	if p.isLabel() {
+		p.advance()
+		label = p.advance().Lexeme
+	}
+
+	if p.match(l.FOR) {
+		return p.forStatement(label)
+	} else if p.match(l.PUT) {
+		return p.putStatement()
+	} else if p.match(l.WHILE, l.UNTIL) {
+		return p.whileStatement(label)
+	} else if p.match(l.DO) {
+		return p.doStatement(label)
+	} else if p.match(l.LOOP) {
+		return p.loopStatement(label)
+	} else if p.match(l.RETURN) {
+		return p.returnStatement()
+	} else if p.match(l.BREAK, l.CONTINUE) {
+		return p.breakStatement()
+	} else if p.match(l.TRIGGER) {
+		return p.triggerStatement()
+	} else if p.match(l.PULSE) {
+		return p.pulseStatement()
+	} else if p.match(l.GO_OUT) {
+		return p.spawnStatement()
+	} else if p.match(l.ERROR) {
+		return p.errorStatement()
+	} else if found, name := p.peekN(1); p.check(l.AT) && found && name.Type == l.IDENTIFIER {
+		return p.joinPoint()
+	}
+
+	return p.expressionStatement()
+}
+
+// triggerStatement → "trigger" identifier ( "(" ( assign ( "," assign )* )? ")" | primary* )
+func (p *Parser) triggerStatement() (Stmt, error) {
+	keyword := p.previous()
+	event, err := p.consume(l.IDENTIFIER)
+	if err != nil {
+		return nil, err
+	}
+
+	t := TriggerStmt{Keyword: keyword, Event: event, Args: make([]Expr, 0)}
+	if p.match(l.LEFT_PAREN) {
+		if t.Args, err = p.arguments(); err != nil {
+			return nil, err
+		}
+	} else {
+		for p.startsArgument() {
+			arg, err := p.primary()
+			if err != nil {
+				return nil, err
+			}
+			t.Args = append(t.Args, arg)
+		}
+	}
+
+	if !p.match(l.NEW_LINE, l.SEMICOLON) && !p.check(l.RIGHT_BRACE) && !p.isAtEnd() {
+		t := p.peek()
+		return nil, e.Error(t.Line, t.Column, t.Lexeme, e.PARSER, "expect new line after trigger")
+	}
+	return t, nil
+}
+
+// spawnStatement → "!>" expression "\n"
+func (p *Parser) spawnStatement() (Stmt, error) {
+	keyword := p.previous()
+	task, err := p.expression()
+	if err != nil {
+		return nil, err
+	}
+
+	if !p.match(l.NEW_LINE, l.SEMICOLON) && !p.check(l.RIGHT_BRACE) && !p.isAtEnd() {
+		t := p.peek()
+		return nil, e.Error(t.Line, t.Column, t.Lexeme, e.PARSER, "expect new line after task")
+	}
+	return SpawnStmt{Keyword: keyword, Task: task}, nil
+}
+
+// errorStatement → "error" ( identifier expression? | expression )? ( "=>" expression )? "\n"
+func (p *Parser) errorStatement() (Stmt, error) {
+	x := ErrorStmt{Keyword: p.previous()}
+	var err error
+
+	// `error is_three "deu ruim"` names a kind, `error e` may name a kind or a variable,
+	// which is only known once it is resolved
+	if _, next := p.peekN(1); p.check(l.IDENTIFIER) && !p.startsErrLiteral() && (p.endsStatement(next.Type) || next.Type == l.RETURN || next.Type == l.STRING_LITERAL || next.Type == l.IDENTIFIER) {
+		x.Kind = p.advance()
+		if !p.endsStatement(p.peek().Type) && !p.check(l.RETURN) {
+			if x.Message, err = p.expression(); err != nil {
+				return nil, err
+			}
+		}
+	} else if !p.endsStatement(p.peek().Type) && !p.check(l.RETURN) {
+		if x.Value, err = p.expression(); err != nil {
+			return nil, err
 		}
+	}
 
-		s, err := p.declaration()
-		if err != nil {
-			return stmt, err
+	if p.match(l.RETURN) {
+		arrow := p.previous()
+		if p.Functions == 0 {
+			return nil, e.Error(arrow.Line, arrow.Column, arrow.Lexeme, e.PARSER, "cannot return outside a function")
 		}
+		if x.Results, err = p.expression(); err != nil {
+			return nil, err
+		}
+		x.Results = results(x.Results)
+	}
 
-		stmt = append(stmt, s)
+	if !p.match(l.NEW_LINE, l.SEMICOLON) && !p.check(l.RIGHT_BRACE) && !p.isAtEnd() {
+		t := p.peek()
+		return nil, e.Error(t.Line, t.Column, t.Lexeme, e.PARSER, "expect new line after error")
 	}
+	return x, nil
+}
 
-	return stmt, nil
+func (p *Parser) endsStatement(t l.TokenType) bool {
+	return t == l.NEW_LINE || t == l.SEMICOLON || t == l.RIGHT_BRACE || t == l.EOF
 }
 
-func (p *Parser) declaration() (Stmt, error) {
-	if p.match(l.LET) {
-		s, err := p.letStatement()
+// pulseStatement → "pulse" ( ( "before" | "after" ) identifier | "inside" identifier label ) block
+func (p *Parser) pulseStatement() (Stmt, error) {
+	s := PulseStmt{Keyword: p.previous()}
+	if p.match(l.UNTIL, l.WHILE) {
+		return p.reactiveStatement(s.Keyword)
+	}
+	if !p.match(l.BEFORE, l.INSIDE, l.AFTER) {
+		t := p.peek()
+		return nil, e.Error(t.Line, t.Column, t.Lexeme, e.PARSER, "expect before, inside, after, until or while after pulse")
+	}
+	s.When = p.previous()
 
-		if err != nil {
-			p.Synchronize()
-			return s, err
+	target, err := p.consume(l.IDENTIFIER)
+	if err != nil {
+		return nil, err
+	}
+	s.Target = Identifier{Name: target}
+
+	if s.When.Type == l.INSIDE {
+		if _, err := p.consume(l.AT); err != nil {
+			return nil, err
+		}
+		if s.Label, err = p.consume(l.IDENTIFIER); err != nil {
+			return nil, err
 		}
+	}
 
-		return s, err
+	// the injected code cannot leave the function or the loops around it
+	loops, functions := p.loops, p.Functions
+	p.loops, p.Functions = nil, 0
+	if err = p.ensureNotUnterminated(); err == nil {
+		s.Body, err = p.block(true)
+	}
+	p.loops, p.Functions = loops, functions
+	if err != nil {
+		return nil, err
 	}
 
-	return p.statement()
+	return s, nil
 }
 
-func (p *Parser) letStatement() (Stmt, error) {
-	var mutable, nullable bool
-	var initializer Expr
-	var name, varType l.Token
-	var err error
-
-	if p.match(l.BANG) {
-		mutable = true
+// reactiveStatement → "pulse" ( "until" | "while" ) expression block
+func (p *Parser) reactiveStatement(keyword l.Token) (Stmt, error) {
+	until := p.previous().Type == l.UNTIL
+	condition, err := p.expression()
+	if err != nil {
+		return nil, err
 	}
-	if p.match(l.CHECK) {
-		nullable = true
+	if err = p.ensureNotUnterminated(); err != nil {
+		return nil, err
 	}
 
-	name, err = p.consume(l.IDENTIFIER)
+	body, _, err := p.loopBody("")
 	if err != nil {
 		return nil, err
 	}
+	return ReactiveStmt{Keyword: keyword, Condition: condition, Body: body, Until: until}, nil
+}
 
-	varType = l.Token{Type: l.UNDEFINED, Lexeme: "", Literal: "", Line: name.Line, Column: name.Column}
-	if p.match(l.COLON) {
-		varType = p.advance()
-		if !varType.Type.IsValidType() {
-			return nil, e.Error(varType.Line, varType.Column, varType.Lexeme, e.PARSER, "expect type in let statement")
-		}
+// joinPoint → label "\n", marks where the code of `pulse inside` runs
+func (p *Parser) joinPoint() (Stmt, error) {
+	p.advance()
+	label := p.advance()
+
+	if !p.match(l.NEW_LINE, l.SEMICOLON) && !p.check(l.RIGHT_BRACE) && !p.isAtEnd() {
+		t := p.peek()
+		return nil, e.Error(t.Line, t.Column, t.Lexeme, e.PARSER, "expect new line after join point")
 	}
+	return JoinPoint{Label: label}, nil
+}
 
-	if p.match(l.ASSIGN) {
-		initializer, err = p.expression()
+// isLabel checks for the `@name` before a loop
+func (p *Parser) isLabel() bool {
+	if !p.check(l.AT) {
+		return false
+	}
+
+	found, name := p.peekN(1)
+	if !found || name.Type != l.IDENTIFIER {
+		return false
+	}
+
+	found, loop := p.peekN(2)
+	switch loop.Type {
+	case l.FOR, l.WHILE, l.UNTIL, l.DO, l.LOOP:
+		return found
+	default:
+		return false
+	}
+}
+
+// loopBody parses the block of a loop, telling if a break or continue aims at it
+func (p *Parser) loopBody(label string) (Expr, bool, error) {
+	p.loops = append(p.loops, loopLabel{name: label})
+	body, err := p.block(true)
+	breaks := p.loops[len(p.loops)-1].breaks
+	p.loops = p.loops[:len(p.loops)-1]
+
+	return body, breaks, err
+}
+
+func (p *Parser) breakStatement() (Stmt, error) {
+	keyword := p.previous()
+
+	label := ""
+	if p.match(l.AT) {
+		name, err := p.consume(l.IDENTIFIER)
 		if err != nil {
-			return initializer, err
+			return nil, err
 		}
+		label = name.Lexeme
 	}
 
-	// Bad smell, but it's working... So, it's a problem for the future when it broke the whole thing
-	if t := p.peek().Type; t != l.RIGHT_BRACE && t != l.SEMICOLON {
-		if _, err := p.consume(l.NEW_LINE); err != nil {
-			t := p.peek()
-			return nil, e.Error(t.Line, t.Column, t.Lexeme, e.PARSER, "expect new line after let statement")
+	target := -1
+	for i := len(p.loops) - 1; i >= 0; i-- {
+		if label == "" || p.loops[i].name == label {
+			target = i
+			break
 		}
 	}
 
-	return LetStmt{Name: name, Mutable: mutable, Nullable: nullable, Type: tokenToType(varType), Initializer: initializer}, nil
-}
+	if target < 0 && label != "" {
+		return nil, e.Error(keyword.Line, keyword.Column, keyword.Lexeme, e.PARSER, fmt.Sprintf("there is no loop labeled @%s around %s", label, keyword.Lexeme))
+	} else if target < 0 {
+		return nil, e.Error(keyword.Line, keyword.Column, keyword.Lexeme, e.PARSER, fmt.Sprintf("cannot %s outside a loop", keyword.Lexeme))
+	}
+	p.loops[target].breaks = true
 
-func (p *Parser) statement() (Stmt, error) {
-	if p.match(l.FOR) {
-		return p.forStatement()
-	} else if p.match(l.PUT) {
-		return p.putStatement()
-	} else if p.match(l.WHILE) {
-		return p.whileStatement()
+	if t := p.peek().Type; t != l.RIGHT_BRACE && t != l.SEMICOLON && t != l.EOF {
+		if _, err := p.consume(l.NEW_LINE); err != nil {
+			t := p.peek()
+			return nil, e.Error(t.Line, t.Column, t.Lexeme, e.PARSER, fmt.Sprintf("expect new line after %s", keyword.Lexeme))
+		}
 	}
 
-	return p.expressionStatement()
+	if keyword.Type == l.CONTINUE {
+		return ContinueStmt{Keyword: keyword, Label: label}, nil
+	}
+	return BreakStmt{Keyword: keyword, Label: label}, nil
 }
 
 func (p *Parser) ifStatement() (Expr, error) {
@@ -137,11 +1013,16 @@ func (p *Parser) ifStatement() (Expr, error) {
 	return IfStmt{Condition: condition, Then: thenBranch, Else: elseBranch}, nil
 }
 
-func (p *Parser) forStatement() (Stmt, error) {
+func (p *Parser) forStatement(label string) (Stmt, error) {
 	var condition, increment, body Expr
 	var initializer Stmt
+	var breaks bool
 	var err error
 
+	if p.isForIn() {
+		return p.forInStatement(label)
+	}
+
 	// Declaration
 	if p.match(l.LET) {
 		initializer, err = p.letStatement()
@@ -172,21 +1053,15 @@ func (p *Parser) forStatement() (Stmt, error) {
 		}
 	}
 
-	if body, err = p.block(true); err != nil {
+	if body, breaks, err = p.loopBody(label); err != nil {
 		return nil, err
 	}
 
-	if increment != nil {
-		s := (body.(Block))
-		s.Scope.Statements = append(s.Scope.Statements, increment)
-		body = s
-	}
-
 	if condition == nil {
 		condition = Literal{true}
 	}
 
-	body = WhileStmt{Condition: condition, Body: body}
+	body = WhileStmt{Condition: condition, Body: body, Increment: increment, Label: label, Breaks: breaks}
 
 	if initializer != nil {
 		var scope Scope
@@ -198,6 +1073,65 @@ func (p *Parser) forStatement() (Stmt, error) {
 	return body, nil
 }
 
+// isForIn looks for the `in` that separates `for x in xs` from `for let x = 0; ...`
+func (p *Parser) isForIn() bool {
+	for i := p.Current; i < len(p.Tokens); i++ {
+		switch p.Tokens[i].Type {
+		case l.IN:
+			return true
+		case l.SEMICOLON, l.LEFT_BRACE, l.NEW_LINE, l.EOF:
+			return false
+		}
+	}
+	return false
+}
+
+// for ( "let" )? identifier ( ":"? type )? ( "," identifier ( ":"? type )? )? "in" expression block
+func (p *Parser) forInStatement(label string) (Stmt, error) {
+	var err error
+
+	f := ForInStmt{Keyword: p.previous(), Label: label}
+	p.match(l.LET)
+
+	for {
+		name, err := p.consume(l.IDENTIFIER)
+		if err != nil {
+			return nil, err
+		}
+
+		v := LetStmt{Name: name, Type: UNDEFINED}
+		if p.match(l.COLON) || p.startsType() {
+			a, err := p.typeAnnotation()
+			if err != nil {
+				return nil, err
+			}
+			v.Type, v.Elem, v.Key, v.Object, v.Nullable, v.Parts, v.Size = a.Type, a.Elem, a.Key, a.Object, a.Nullable, a.Parts, a.Size
+		}
+		f.Vars = append(f.Vars, v)
+
+		if len(f.Vars) == 2 || !p.match(l.COMMA) {
+			break
+		}
+	}
+
+	if _, err = p.consume(l.IN); err != nil {
+		return nil, err
+	}
+
+	if f.Collection, err = p.expression(); err != nil {
+		return nil, err
+	}
+	if err = p.ensureNotUnterminated(); err != nil {
+		return nil, err
+	}
+
+	if f.Body, f.Breaks, err = p.loopBody(label); err != nil {
+		return nil, err
+	}
+
+	return f, nil
+}
+
 func (p *Parser) putStatement() (Stmt, error) {
 	expr, err := p.expression()
 	if err != nil {
@@ -217,10 +1151,12 @@ func (p *Parser) putStatement() (Stmt, error) {
 	return PutStmt{Value: expr}, nil
 }
 
-func (p *Parser) whileStatement() (Stmt, error) {
+func (p *Parser) whileStatement(label string) (Stmt, error) {
 	var expr, body Expr
+	var breaks bool
 	var err error
 
+	until := p.previous().Type == l.UNTIL
 	if expr, err = p.expression(); err != nil {
 		return nil, err
 	}
@@ -228,11 +1164,58 @@ func (p *Parser) whileStatement() (Stmt, error) {
 		return nil, err
 	}
 
-	if body, err = p.block(true); err != nil {
+	if body, breaks, err = p.loopBody(label); err != nil {
+		return nil, err
+	}
+
+	return WhileStmt{Condition: expr, Body: body, Until: until, Label: label, Breaks: breaks}, nil
+}
+
+func (p *Parser) doStatement(label string) (Stmt, error) {
+	var err error
+
+	d := DoStmt{Label: label}
+	if err = p.ensureNotUnterminated(); err != nil {
+		return nil, err
+	}
+	if d.Body, d.Breaks, err = p.loopBody(label); err != nil {
+		return nil, err
+	}
+
+	if err = p.ensureNotUnterminated(); err != nil {
+		return nil, err
+	}
+	if !p.match(l.WHILE, l.UNTIL) {
+		t := p.peek()
+		return nil, e.Error(t.Line, t.Column, t.Lexeme, e.PARSER, "expect while or until after do block")
+	}
+	d.Until = p.previous().Type == l.UNTIL
+
+	if d.Condition, err = p.expression(); err != nil {
+		return nil, err
+	}
+
+	return d, nil
+}
+
+func (p *Parser) loopStatement(label string) (Stmt, error) {
+	var err error
+
+	loop := LoopStmt{Keyword: p.previous(), Label: label}
+	if !p.check(l.LEFT_BRACE) {
+		if loop.Times, err = p.expression(); err != nil {
+			return nil, err
+		}
+	}
+	if err = p.ensureNotUnterminated(); err != nil {
+		return nil, err
+	}
+
+	if loop.Body, loop.Breaks, err = p.loopBody(label); err != nil {
 		return nil, err
 	}
 
-	return WhileStmt{Condition: expr, Body: body}, nil
+	return loop, nil
 }
 
 // A state that contains an expression
@@ -262,11 +1245,162 @@ func (p *Parser) expression() (Expr, error) {
 func (p *Parser) statementExpression() (Expr, error) {
 	if p.match(l.IF) {
 		return p.ifStatement()
+	} else if p.match(l.CASE) {
+		return p.caseExpression()
 	}
 
 	return p.sequence()
 }
 
+func (p *Parser) caseExpression() (Expr, error) {
+	var err error
+
+	c := Case{Keyword: p.previous()}
+	if c.Subject, err = p.expression(); err != nil {
+		return nil, err
+	}
+
+	for p.nextArm(l.OF) {
+		arm, err := p.arm()
+		if err != nil {
+			return nil, err
+		}
+		c.Arms = append(c.Arms, arm)
+	}
+
+	if p.nextArm(l.ELSE) {
+		if _, err = p.consume(l.RETURN); err != nil {
+			return nil, err
+		}
+		if c.Else, err = p.armBody(); err != nil {
+			return nil, err
+		}
+	}
+
+	if len(c.Arms) == 0 && c.Else == nil {
+		if err := p.ensureNotUnterminated(); err != nil {
+			return nil, err
+		}
+		t := p.peek()
+		return nil, e.Error(t.Line, t.Column, t.Lexeme, e.PARSER, "expect of after case")
+	}
+
+	return c, nil
+}
+
+// nextArm looks past the new lines for the `of` or `else` of a case, leaving them alone when it is not there
+func (p *Parser) nextArm(t l.TokenType) bool {
+	i := p.Current
+	for i < len(p.Tokens) && p.Tokens[i].Type == l.NEW_LINE {
+		i++
+	}
+
+	if i < len(p.Tokens) && p.Tokens[i].Type == t {
+		p.Current = i + 1
+		return true
+	}
+	return false
+}
+
+func (p *Parser) arm() (Arm, error) {
+	var arm Arm
+	var err error
+
+	for {
+		pattern, err := p.pattern(false)
+		if err != nil {
+			return arm, err
+		}
+		arm.Patterns = append(arm.Patterns, pattern)
+		if !p.match(l.OR_BITWISE) {
+			break
+		}
+	}
+
+	if p.match(l.IF) {
+		if arm.Guard, err = p.logic(); err != nil {
+			return arm, err
+		}
+	}
+
+	if _, err = p.consume(l.RETURN); err != nil {
+		return arm, err
+	}
+
+	arm.Body, err = p.armBody()
+	return arm, err
+}
+
+func (p *Parser) armBody() (Expr, error) {
+	if err := p.ensureNotUnterminated(); err != nil {
+		return nil, err
+	}
+
+	if p.match(l.PUT) {
+		value, err := p.expression()
+		return PutStmt{Value: value}, err
+	}
+
+	return p.expression()
+}
+
+// pattern reads a type, a list, a range or a value, identifiers inside lists are bindings
+func (p *Parser) pattern(inList bool) (Expr, error) {
+	t := p.peek()
+
+	if t.Type.IsType() || t.Type == l.FN {
+		a, err := p.typeAnnotation()
+		return TypePattern{Name: t, Type: a.Type, Nullable: a.Nullable}, err
+	}
+
+	if p.match(l.LEFT_BRACKET) {
+		return p.listPattern()
+	}
+
+	if t.Type == l.IDENTIFIER && (inList || t.Lexeme == "_") {
+		p.advance()
+		return Binding{Name: t}, nil
+	}
+
+	left, err := p.term()
+	if err != nil {
+		return nil, err
+	}
+
+	return p.rangeOf(left, p.term)
+}
+
+func (p *Parser) listPattern() (Expr, error) {
+	list := ListPattern{Bracket: p.previous()}
+
+	for !p.check(l.RIGHT_BRACKET) {
+		element, err := p.pattern(true)
+		if err != nil {
+			return nil, err
+		}
+
+		// `tail...` takes every element left
+		if b, ok := element.(Binding); ok && p.match(l.RANGE_DOT) {
+			if _, err := p.consume(l.DOT); err != nil {
+				return nil, err
+			}
+			list.Rest = b
+			break
+		}
+
+		list.Elements = append(list.Elements, element)
+		if !p.match(l.COMMA) {
+			break
+		}
+	}
+
+	if _, err := p.consume(l.RIGHT_BRACKET); err != nil {
+		return nil, err
+	}
+
+	return list, nil
+}
+
 func (p *Parser) sequence() (Expr, error) {
 	expr, err := p.assign()
 	if err != nil {
@@ -290,6 +1424,12 @@ func (p *Parser) assign() (Expr, error) {
 		return expr, err
 	}
 
+	if p.match(l.GO_IN) {
+		arrow := p.previous()
+		value, err := p.expression()
+		return Send{Channel: expr, Arrow: arrow, Value: value}, err
+	}
+
 	for p.match(l.ASSIGN, l.ADD_ASSIGN, l.SUB_ASSIGN, l.MUL_ASSIGN, l.DIV_ASSIGN, l.MOD_ASSIGN, l.POW_ASSIGN, l.BITSHIFT_LEFT_ASSIGN, l.BITSHIFT_RIGHT_ASSIGN, l.ROUNDSHIFT_LEFT_ASSIGN, l.ROUNDSHIFT_RIGHT_ASSIGN, l.AND_ASSIGN, l.OR_ASSIGN, l.XOR_ASSIGN, l.NAND_ASSIGN, l.NOR_ASSIGN, l.XNOR_ASSIGN) {
 		op := p.previous()
 		right, err := p.expression()
@@ -300,8 +1440,17 @@ func (p *Parser) assign() (Expr, error) {
 		switch i := expr.(type) {
 		case Identifier:
 			expr = Assign{Target: i.Name, Operator: op, Value: right}
+		case PositionAccess:
+			expr = PositionAssign{Target: i, Operator: op, Value: right}
+		case Access:
+			expr = AccessAssign{Target: i, Operator: op, Value: right}
+		case Pointer:
+			if i.Operator.Type != l.STAR {
+				return expr, e.Error(op.Line, op.Column, op.Lexeme, e.PARSER, "cannot assign to an address, only to what a pointer points to")
+			}
+			expr = PointerAssign{Target: i, Operator: op, Value: right}
 		default:
-			return expr, e.Error(op.Line, op.Column, op.Lexeme, e.PARSER, "assignment target should be a identifier")
+			return expr, e.Error(op.Line, op.Column, op.Lexeme, e.PARSER, "assignment target should be a identifier, a position, a field or a pointer")
 		}
 	}
 
@@ -334,7 +1483,7 @@ func (p *Parser) pipeline() (Expr, error) {
 }
 
 func (p *Parser) ternary() (Expr, error) {
-	expr, err := p.interval()
+	expr, err := p.membership()
 	if err != nil {
 		return expr, err
 	}
@@ -350,12 +1499,31 @@ func (p *Parser) ternary() (Expr, error) {
 			return expr, err
 		}
 
-		falseExpr, err := p.expression()
+		falseExpr, err := p.expression()
+		if err != nil {
+			return expr, err
+		}
+
+		expr = Ternary{Expression: expr, True: trueExpr, False: falseExpr}
+	}
+
+	return expr, nil
+}
+
+func (p *Parser) membership() (Expr, error) {
+	expr, err := p.interval()
+	if err != nil {
+		return expr, err
+	}
+
+	for p.match(l.IN) {
+		op := p.previous()
+		right, err := p.interval()
 		if err != nil {
 			return expr, err
 		}
 
-		expr = Ternary{Expression: expr, True: trueExpr, False: falseExpr}
+		expr = Membership{Left: expr, Operator: op, Right: right}
 	}
 
 	return expr, nil
@@ -367,16 +1535,29 @@ func (p *Parser) interval() (Expr, error) {
 		return expr, err
 	}
 
+	return p.rangeOf(expr, p.logic)
+}
+
+// rangeOf reads the `..b`, `..<b` and `..b..step` after the first endpoint of a range, if any
+func (p *Parser) rangeOf(left Expr, endpoint func() (Expr, error)) (Expr, error) {
+	if !p.match(l.RANGE_DOT) {
+		return left, nil
+	}
+
+	r := Range{Left: left, Operator: p.previous(), Exclusive: p.match(l.LESS)}
+
+	var err error
+	if r.Right, err = endpoint(); err != nil {
+		return nil, err
+	}
+
 	if p.match(l.RANGE_DOT) {
-		right, err := p.expression()
-		if err != nil {
-			return expr, err
+		if r.Step, err = endpoint(); err != nil {
+			return nil, err
 		}
-
-		expr = Range{expr, right}
 	}
 
-	return expr, nil
+	return r, nil
 }
 
 func (p *Parser) logic() (Expr, error) {
@@ -554,6 +1735,16 @@ func (p *Parser) pointer() (Expr, error) {
 		return Pointer{op, right}, err
 	}
 
+	// `**p` is read as a power operator, but here it follows a pointer twice
+	if p.match(l.POW) {
+		op := p.previous()
+		first, second := op, op
+		first.Type, first.Lexeme = l.STAR, "*"
+		second.Type, second.Lexeme, second.Column = l.STAR, "*", op.Column+1
+		right, err := p.pointer()
+		return Pointer{first, Pointer{second, right}}, err
+	}
+
 	return p.unary()
 }
 
@@ -567,8 +1758,9 @@ func (p *Parser) unary() (Expr, error) {
 	return p.access()
 }
 
+// access reads a navigation chain, a `?:` after it applies to the whole chain
 func (p *Parser) access() (Expr, error) {
-	expr, err := p.validate()
+	expr, err := p.catch()
 	if err != nil {
 		return expr, err
 	}
@@ -582,46 +1774,41 @@ func (p *Parser) access() (Expr, error) {
 				return expr, err
 			}
 
-			expr = PositionAccess{Expression: expr, Pos: right}
+			expr = PositionAccess{Expression: expr, Bracket: op, Pos: right}
 
 			if _, err := p.consume(l.RIGHT_BRACKET); err != nil {
 				return expr, err
 			}
 		} else {
-			right, err := p.validate()
+			right, err := p.catch()
 			if err != nil {
 				return expr, err
 			}
 
-			expr = Access{Left: expr, Right: right, Operator: op}
+			expr = Access{Left: expr, Right: right, Operator: op, Inside: p.object}
 		}
 
 	}
 
-	return expr, nil
+	return p.validate(expr)
 }
 
-func (p *Parser) validate() (Expr, error) {
-	expr, err := p.catch()
-	if err != nil {
-		return expr, err
+// validate reads `x ?: y` and `x ?:`, which gives the zero of the type of x when it is nil
+func (p *Parser) validate(expr Expr) (Expr, error) {
+	if !p.match(l.ELVIS) {
+		return expr, nil
 	}
 
-	if p.match(l.ELVIS) {
-		x := p.peek()
-		if x.Type == l.NEW_LINE || x.Type == l.RIGHT_BRACE || x.Type == l.RIGHT_PAREN || x.Type == l.RIGHT_BRACKET {
-			expr = Elvis{Left: expr, ReturnZero: true, Right: nil}
-		} else {
-			right, err := p.catch()
-			if err != nil {
-				return expr, err
-			}
-
-			expr = Elvis{Left: expr, ReturnZero: false, Right: right}
-		}
+	op := p.previous()
+	if x := p.peek().Type; p.endsStatement(x) || x == l.RIGHT_PAREN || x == l.RIGHT_BRACKET || x == l.COMMA {
+		return Elvis{Left: expr, Operator: op, ReturnZero: true, Type: UNKNOWN}, nil
 	}
 
-	return expr, nil
+	right, err := p.access()
+	if err != nil {
+		return expr, err
+	}
+	return Elvis{Left: expr, Operator: op, Right: right, Type: UNKNOWN}, nil
 }
 
 func (p *Parser) catch() (Expr, error) {
@@ -630,30 +1817,39 @@ func (p *Parser) catch() (Expr, error) {
 		return expr, err
 	}
 
-	tmp := p.peek()
-	if tmp.Type == l.CHECK {
-		found, x := p.peekN(1)
-		if !found || x.Type == l.NEW_LINE {
-			p.advance()
-			expr = Check{Left: expr, HaveReturn: false, Right: nil}
-		} else {
-			found, t := p.peekN(1)
-			if found && t.Type == l.RETURN {
-				p.advance()
-				p.advance()
-				right, err := p.expression()
-				if err != nil {
-					return expr, err
-				}
-				expr = Check{Left: expr, HaveReturn: true, Right: right}
-			}
+	if !p.check(l.CHECK) {
+		return expr, nil
+	}
+
+	// anything else after the `?` makes it a ternary
+	found, next := p.peekN(1)
+	switch {
+	case !found || p.endsStatement(next.Type) || next.Type == l.RIGHT_PAREN || next.Type == l.RIGHT_BRACKET || next.Type == l.COMMA:
+		return Check{Left: expr, Mark: p.advance()}, nil
+	case next.Type == l.RETURN:
+		mark := p.advance()
+		arrow := p.advance()
+		if p.Functions == 0 {
+			return nil, e.Error(arrow.Line, arrow.Column, arrow.Lexeme, e.PARSER, "cannot return outside a function")
+		}
+		right, err := p.expression()
+		if err != nil {
+			return expr, err
+		}
+		return Check{Left: expr, Mark: mark, HaveReturn: true, Right: results(right)}, nil
+	case next.Type == l.LEFT_BRACE:
+		mark := p.advance()
+		handler, err := p.block(true)
+		if err != nil {
+			return expr, err
 		}
+		return Check{Left: expr, Mark: mark, Handler: handler}, nil
 	}
 	return expr, nil
 }
 
 func (p *Parser) cast() (Expr, error) {
-	expr, err := p.primary()
+	expr, err := p.call()
 	if err != nil {
 		return expr, err
 	}
@@ -678,9 +1874,113 @@ func (p *Parser) cast() (Expr, error) {
 	return expr, nil
 }
 
+// Arguments go either between parenthesis `f(a, b)` or juxtaposed to the callee `f a b`
+func (p *Parser) call() (Expr, error) {
+	expr, err := p.primary()
+	if err != nil {
+		return expr, err
+	}
+
+	if !isCallable(expr) {
+		return expr, nil
+	}
+
+	for p.check(l.LEFT_PAREN) {
+		paren := p.advance()
+		args, err := p.arguments()
+		if err != nil {
+			return expr, err
+		}
+
+		expr = Call{Callee: expr, Token: callToken(expr, paren), Args: args}
+	}
+
+	if _, ok := expr.(Call); ok || !p.startsArgument() {
+		return expr, nil
+	}
+
+	args := make([]Expr, 0)
+	for p.startsArgument() {
+		arg, err := p.primary()
+		if err != nil {
+			return expr, err
+		}
+		args = append(args, arg)
+	}
+
+	return Call{Callee: expr, Token: callToken(expr, p.previous()), Args: args}, nil
+}
+
+// arguments reads `a, b)` after the opening parenthesis of a call
+func (p *Parser) arguments() ([]Expr, error) {
+	args := make([]Expr, 0)
+
+	if !p.check(l.RIGHT_PAREN) {
+		for {
+			arg, err := p.assign()
+			if err != nil {
+				return nil, err
+			}
+			args = append(args, arg)
+			if !p.match(l.COMMA) {
+				break
+			}
+		}
+	}
+
+	if _, err := p.consume(l.RIGHT_PAREN); err != nil {
+		return nil, err
+	}
+	return args, nil
+}
+
+func (p *Parser) startsArgument() bool {
+	switch p.peek().Type {
+	case l.IDENTIFIER, l.STRING_LITERAL, l.NUMBER_LITERAL, l.FLOAT_LITERAL, l.TRUE, l.FALSE, l.NIL, l.LEFT_PAREN:
+		return true
+	default:
+		return false
+	}
+}
+
+func isCallable(expr Expr) bool {
+	switch expr.(type) {
+	case Identifier, Grouping, Lambda:
+		return true
+	default:
+		return false
+	}
+}
+
+func callToken(callee Expr, fallback l.Token) l.Token {
+	if i, ok := callee.(Identifier); ok {
+		return i.Name
+	}
+	return fallback
+}
+
 func (p *Parser) primary() (Expr, error) {
 	if p.match(l.IDENTIFIER) {
-		return Identifier{p.previous()}, nil
+		name := p.previous()
+		if next := p.peek(); next.Type == l.LEFT_BRACE && next.Line == name.Line && next.Column == name.Column+len(name.Lexeme) {
+			if name.Lexeme == errName {
+				return p.errLiteral(name)
+			}
+			return p.objectLiteral(name)
+		}
+		if p.match(l.QUOTE) {
+			return Move{Variable: Identifier{Name: name}, Quote: p.previous()}, nil
+		}
+		if p.match(l.BANG) {
+			return Borrow{Variable: Identifier{Name: name}, Bang: p.previous()}, nil
+		}
+		return Identifier{Name: name}, nil
+	}
+	if p.match(l.THIS) {
+		return Identifier{Name: p.previous()}, nil
+	}
+	if p.match(l.CHAN) {
+		return p.channelLiteral()
 	}
 	if p.match(l.STRING_LITERAL, l.NUMBER_LITERAL, l.FLOAT_LITERAL) {
 		return Literal{p.previous().Literal}, nil
@@ -701,18 +2001,219 @@ func (p *Parser) primary() (Expr, error) {
 	return p.mapLiteral()
 }
 
+// channelLiteral → "chan" type ( ":" expression )?, the expression is how many values it holds without a receiver
+func (p *Parser) channelLiteral() (Expr, error) {
+	c := ChannelLiteral{Keyword: p.previous()}
+	elem, err := p.typeAnnotation()
+	if err != nil {
+		return nil, err
+	}
+	c.Type = elem.Type
+
+	if p.match(l.COLON) {
+		if c.Size, err = p.expression(); err != nil {
+			return nil, err
+		}
+	}
+	return c, nil
+}
+
+// mapLiteral → array | slice | tuple | map, tuples are read by group since they start like one
 func (p *Parser) mapLiteral() (Expr, error) {
 	current := p.peek()
 	if current.Type == l.LEFT_BRACKET {
-		if _, next := p.peekN(1); next.Type.IsType() {
+		_, next := p.peekN(1)
+		_, after := p.peekN(2)
+		if next.Type.IsType() && after.Type == l.COLON {
 			return p.arrayLiteral()
 		}
-	} /* else if x.Type == l.LEFT_PAREN {
+		return p.sliceLiteral()
+	} else if current.Type == l.OR_BITWISE {
+		return p.mapValue()
+	}
+	return p.group()
+}
 
-	} else if x.Type == l.OR_BITWISE {
+// startsErrLiteral tells if `err{` comes next
+func (p *Parser) startsErrLiteral() bool {
+	name := p.peek()
+	found, next := p.peekN(1)
+	return name.Lexeme == errName && found && next.Type == l.LEFT_BRACE && next.Line == name.Line && next.Column == name.Column+len(name.Lexeme)
+}
 
-	} */
-	return p.group()
+// errLiteral reads `err{type: kind, msg: "message"}`, both are optional and the kind is a name, not a variable
+func (p *Parser) errLiteral(keyword l.Token) (Expr, error) {
+	x := ErrLiteral{Keyword: keyword}
+	p.advance()
+
+	for {
+		if err := p.ensureNotUnterminated(); err != nil {
+			return nil, err
+		}
+		if p.match(l.RIGHT_BRACE) {
+			return x, nil
+		}
+
+		field, err := p.consume(l.IDENTIFIER)
+		if err != nil {
+			return nil, err
+		}
+		if _, err := p.consume(l.COLON); err != nil {
+			return nil, err
+		}
+
+		switch field.Lexeme {
+		case "type":
+			if x.Kind, err = p.consume(l.IDENTIFIER); err != nil {
+				return nil, err
+			}
+		case "msg":
+			if x.Message, err = p.assign(); err != nil {
+				return nil, err
+			}
+		default:
+			return nil, e.Error(field.Line, field.Column, field.Lexeme, e.PARSER, fmt.Sprintf("err has only type and msg, found: %s", field.Lexeme))
+		}
+
+		if !p.match(l.COMMA) {
+			if err := p.ensureNotUnterminated(); err != nil {
+				return nil, err
+			}
+			if _, err := p.consume(l.RIGHT_BRACE); err != nil {
+				return nil, err
+			}
+			return x, nil
+		}
+	}
+}
+
+// objectLiteral reads `Person{name: "Ann", age: 3}` or `Person{"Ann", 3}`, the brace
+// has to touch the name, otherwise `if done {` would build an object
+func (p *Parser) objectLiteral(name l.Token) (Expr, error) {
+	o := ObjectLiteral{Object: Identifier{Name: name}, Brace: p.advance()}
+
+	for {
+		if err := p.ensureNotUnterminated(); err != nil {
+			return nil, err
+		}
+		if p.match(l.RIGHT_BRACE) {
+			break
+		}
+
+		// the first value decides if all of them are named
+		if _, next := p.peekN(1); (len(o.Values) == 0 || len(o.Names) > 0) && p.check(l.IDENTIFIER) && next.Type == l.COLON {
+			o.Names = append(o.Names, p.advance())
+			p.advance()
+		} else if len(o.Names) > 0 {
+			t := p.peek()
+			return nil, e.Error(t.Line, t.Column, t.Lexeme, e.PARSER, "expect field name, either all the values are named or none")
+		}
+
+		value, err := p.assign()
+		if err != nil {
+			return nil, err
+		}
+		o.Values = append(o.Values, value)
+
+		if !p.match(l.COMMA) {
+			if err := p.ensureNotUnterminated(); err != nil {
+				return nil, err
+			}
+			if _, err := p.consume(l.RIGHT_BRACE); err != nil {
+				return nil, err
+			}
+			break
+		}
+	}
+
+	return o, nil
+}
+
+// sliceLiteral reads `[1, 2]` and `[int][1, 2]`
+func (p *Parser) sliceLiteral() (Expr, error) {
+	bracket := p.advance()
+	slice := SliceLiteral{Bracket: bracket, Type: UNKNOWN}
+
+	if _, after := p.peekN(1); p.peek().Type.IsType() && after.Type == l.RIGHT_BRACKET {
+		slice.Type = tokenToType(p.advance())
+		if p.previous().Type == l.ANY {
+			slice.Type = UNDEFINED
+		} else if slice.Type == UNKNOWN {
+			t := p.previous()
+			return nil, e.Error(t.Line, t.Column, t.Lexeme, e.PARSER, fmt.Sprintf("slices of %s are not supported", t.Lexeme))
+		}
+		p.advance()
+		if _, err := p.consume(l.LEFT_BRACKET); err != nil {
+			return nil, err
+		}
+	}
+
+	if p.match(l.RIGHT_BRACKET) {
+		return slice, nil
+	}
+
+	for {
+		value, err := p.assign()
+		if err != nil {
+			return nil, err
+		}
+		slice.Values = append(slice.Values, value)
+		if !p.match(l.COMMA) {
+			break
+		}
+	}
+
+	if _, err := p.consume(l.RIGHT_BRACKET); err != nil {
+		return nil, err
+	}
+
+	return slice, nil
+}
+
+// mapValue reads `|string: int|` and an optional `{"a": 1, "b": 2}` with the first entries
+func (p *Parser) mapValue() (Expr, error) {
+	bar := p.advance()
+	key, value, err := p.mapType()
+	if err != nil {
+		return nil, err
+	}
+
+	m := MapLiteral{Bar: bar, Key: key.Type, Value: value.Type}
+	if !p.match(l.LEFT_BRACE) || p.match(l.RIGHT_BRACE) {
+		return m, nil
+	}
+
+	for {
+		if err := p.ensureNotUnterminated(); err != nil {
+			return nil, err
+		}
+		k, err := p.assign()
+		if err != nil {
+			return nil, err
+		}
+		if _, err := p.consume(l.COLON); err != nil {
+			return nil, err
+		}
+		v, err := p.assign()
+		if err != nil {
+			return nil, err
+		}
+
+		m.Keys = append(m.Keys, k)
+		m.Values = append(m.Values, v)
+		if !p.match(l.COMMA) {
+			break
+		}
+	}
+
+	if err := p.ensureNotUnterminated(); err != nil {
+		return nil, err
+	}
+	if _, err := p.consume(l.RIGHT_BRACE); err != nil {
+		return nil, err
+	}
+
+	return m, nil
 }
 
 func (p *Parser) arrayLiteral() (Expr, error) {
@@ -735,13 +2236,13 @@ func (p *Parser) arrayLiteral() (Expr, error) {
 		return nil, err
 	}
 
-	if p.check(l.RIGHT_BRACKET) {
+	if p.match(l.RIGHT_BRACKET) {
 		return ArrayLiteral{Typing: arrayType, Size: arraySize, Values: []Expr{}}, nil
 	} else {
 		var values []Expr
 
 		for {
-			value, err := p.expression()
+			value, err := p.assign()
 			if err != nil {
 				return nil, err
 			}
@@ -751,7 +2252,7 @@ func (p *Parser) arrayLiteral() (Expr, error) {
 			}
 		}
 
-		if !p.match(l.RIGHT_BRACKET) {
+		if _, err := p.consume(l.RIGHT_BRACKET); err != nil {
 			return nil, err
 		}
 
@@ -760,7 +2261,16 @@ func (p *Parser) arrayLiteral() (Expr, error) {
 }
 
 func (p *Parser) group() (Expr, error) {
+	if p.isLambda() {
+		return p.lambda()
+	}
+
+	// `()` and `(a, b)` are tuples, a single expression is only grouped
 	if p.match(l.LEFT_PAREN) {
+		if p.match(l.RIGHT_PAREN) {
+			return Multiple{Values: []Expr{}}, nil
+		}
+
 		expr, err := p.expression()
 		if err != nil {
 			return expr, err
@@ -768,12 +2278,77 @@ func (p *Parser) group() (Expr, error) {
 		if _, err := p.consume(l.RIGHT_PAREN); err != nil {
 			return expr, err
 		}
+
+		if seq, ok := expr.(Sequence); ok {
+			return Multiple{Values: flatten(seq)}, nil
+		}
 		return Grouping{expr}, nil
 	}
 
 	return p.block(false)
 }
 
+// isLambda looks past the parenthesis for the `=>` that separates `(x) => ...` from `(x)`
+func (p *Parser) isLambda() bool {
+	if !p.check(l.LEFT_PAREN) {
+		return false
+	}
+
+	depth := 0
+	for i := p.Current; i < len(p.Tokens); i++ {
+		switch p.Tokens[i].Type {
+		case l.LEFT_PAREN:
+			depth++
+		case l.RIGHT_PAREN:
+			depth--
+			if depth == 0 {
+				return i+1 < len(p.Tokens) && p.Tokens[i+1].Type == l.RETURN
+			}
+		case l.NEW_LINE, l.EOF:
+			return false
+		}
+	}
+
+	return false
+}
+
+// lambda → "(" parameters? ")" "=>" ( ( type ( "," type )* )? block | expression )
+func (p *Parser) lambda() (Expr, error) {
+	var returns []int
+	var body Expr
+
+	paren := p.advance()
+	params, err := p.parameters()
+	if err != nil {
+		return nil, err
+	}
+
+	if _, err := p.consume(l.RETURN); err != nil {
+		return nil, err
+	}
+
+	loops := p.loops
+	p.loops = nil
+	p.Functions++
+	if t := p.peek().Type; t.IsType() || t == l.FN {
+		if returns, err = p.returnTypes(); err == nil {
+			body, err = p.block(true)
+		}
+	} else if t == l.LEFT_BRACE {
+		body, err = p.block(true)
+	} else {
+		body, err = p.expression()
+	}
+	p.Functions--
+	p.loops = loops
+	if err != nil {
+		return nil, err
+	}
+
+	name := l.Token{Type: l.FN, Lexeme: "lambda", Line: paren.Line, Column: paren.Column}
+	return Lambda{Declaration: FnStmt{Name: name, Params: params, Returns: returns, Body: multipleResults(body, returns)}}, nil
+}
+
 func (p *Parser) block(isRequired bool) (Expr, error) {
 	if p.match(l.LEFT_BRACE) {
 		p.Depth++
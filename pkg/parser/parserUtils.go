@@ -127,6 +127,7 @@ func (p *Parser) Synchronize() {
 		case l.ELIF:
 		case l.ELSE:
 		case l.WHEN:
+		case l.TRIGGER:
 		case l.TRAIT:
 		case l.PUT:
 		case l.PRINT:
@@ -0,0 +1,86 @@
+package parser
+
+import "github.com/ToniLommez/Neon_Dream_Runner/pkg/lexer"
+
+// match tests a value against a pattern of a case arm, defining its bindings in s
+func (s *Scope) match(pattern Expr, value any) (bool, error) {
+	switch x := pattern.(type) {
+	case TypePattern:
+		if value == nil {
+			return x.Nullable, nil
+		}
+		return x.Type == UNDEFINED || x.Type == getType(value), nil
+	case Binding:
+		if x.Slot >= 0 {
+			l := LetStmt{Name: x.Name, Type: getType(value), Nullable: true, Initializer: Literal{value}, Slot: x.Slot}
+			if _, err := s.Define(l, value); err != nil {
+				return false, err
+			}
+		}
+		return true, nil
+	case ListPattern:
+		values, ok := snapshot(value)
+		if !ok || len(values) < len(x.Elements) || (x.Rest == nil && len(values) > len(x.Elements)) {
+			return false, nil
+		}
+
+		for i, element := range x.Elements {
+			if matched, err := s.match(element, values[i]); err != nil || !matched {
+				return false, err
+			}
+		}
+
+		if x.Rest != nil {
+			return s.match(x.Rest, Tuple(values[len(x.Elements):]))
+		}
+		return true, nil
+	case Range:
+		r, err := s.RangeEval(x)
+		if err != nil {
+			return false, err
+		}
+		return r.Contains(value), nil
+	default:
+		expected, err := s.evaluate(pattern)
+		if err != nil {
+			return false, err
+		}
+		return same(value, expected), nil
+	}
+}
+
+// snapshot copies the elements of an array or a slice while no task changes them, so they can be gone
+// through without holding shared. Tuples never change, and with no task running there is nothing to copy from
+func snapshot(value any) ([]any, bool) {
+	if _, isTuple := value.(Tuple); isTuple || !concurrent() {
+		return elements(value)
+	}
+	defer viewing()()
+	values, ok := elements(value)
+	return append([]any(nil), values...), ok
+}
+
+// elements gives the values of anything a list pattern can take apart, the ones the collection holds,
+// so what goes through them while tasks run takes a snapshot instead
+func elements(value any) ([]any, bool) {
+	switch v := value.(type) {
+	case Array:
+		return v.Values, true
+	case Tuple:
+		return v, true
+	case Slice:
+		return *v.Values, true
+	default:
+		return nil, false
+	}
+}
+
+// same compares values of a pattern, values that cannot be compared are just different
+func same(a any, b any) bool {
+	if a == nil || b == nil {
+		return a == nil && b == nil
+	}
+
+	eq, err := equality(lexer.Token{Type: lexer.EQUAL}, a, b)
+	return err == nil && eq == true
+}
@@ -0,0 +1,246 @@
+package parser
+
+import (
+	"fmt"
+
+	e "github.com/ToniLommez/Neon_Dream_Runner/pkg/errutils"
+	l "github.com/ToniLommez/Neon_Dream_Runner/pkg/lexer"
+)
+
+// Address is the value of `&x`, `&xs[i]` and `&o.field`. A variable or a field is a slot of a Scope,
+// an element is an Index of the Values of its array or slice. Type is the type of what it points to
+// and ReadOnly is set when the pointer may read but not write, as on an element of an immutable variable
+type Address struct {
+	Scope    *Scope
+	Slot     int
+	Values   *[]any
+	Index    int
+	Type     int
+	Name     string
+	ReadOnly bool
+}
+
+func (a Address) String() string {
+	return "&" + a.Name
+}
+
+// same tells if two addresses point to the same place, the elements of an array are compared by
+// where its values live, since every copy of the array has them at the same place
+func (a Address) same(b Address) bool {
+	if a.Values == nil || b.Values == nil {
+		return a.Values == b.Values && a.Scope == b.Scope && a.Slot == b.Slot
+	}
+	release := viewing()
+	x, y := *a.Values, *b.Values
+	release()
+	return a.Index == b.Index && len(x) > 0 && len(y) > 0 && &x[0] == &y[0]
+}
+
+// token names what the address points to at the place that uses it, for the errors of Get and Set
+func (a Address) token(at l.Token) l.Token {
+	return l.Token{Type: l.IDENTIFIER, Lexeme: a.Name, Line: at.Line, Column: at.Column}
+}
+
+// dangling tells if the address outlived what it points to, a variable of a scope that ended
+// or an element that is no longer in its slice
+func (a Address) dangling(at l.Token) error {
+	if a.Scope != nil && a.Scope.Ended() {
+		return e.Error(at.Line, at.Column, at.Lexeme, e.RUNTIME, fmt.Sprintf("dangling pointer, %s lived in a scope that already ended", a.Name))
+	}
+	if a.Values != nil && a.Index >= a.length() {
+		return e.Error(at.Line, at.Column, at.Lexeme, e.RUNTIME, fmt.Sprintf("dangling pointer, %s is no longer in its collection", a.Name))
+	}
+	return nil
+}
+
+func (a Address) load(at l.Token) (any, error) {
+	if err := a.dangling(at); err != nil {
+		return nil, err
+	}
+	if a.Values != nil {
+		defer viewing()()
+		return (*a.Values)[a.Index], nil
+	}
+	_, v, _, err := a.Scope.Get(a.token(at), 0, a.Slot)
+	return v, err
+}
+
+// store writes through the address, combining the value with the old one on compound assignments.
+// A variable does both while holding its lock, as Update does
+func (a Address) store(at l.Token, operator l.Token, v any) (any, error) {
+	if err := a.dangling(at); err != nil {
+		return nil, err
+	}
+	if a.ReadOnly {
+		return nil, e.Error(at.Line, at.Column, at.Lexeme, e.RUNTIME, fmt.Sprintf("cannot assign through a pointer to %s, it is read only", a.Name))
+	}
+	if a.Values == nil {
+		return a.Scope.Update(a.token(at), 0, a.Slot, operator, v)
+	}
+
+	if operator.Type != l.ASSIGN {
+		old, err := a.load(at)
+		if err != nil {
+			return nil, err
+		}
+		if v, err = compound(operator, old, v); err != nil {
+			return nil, err
+		}
+	}
+
+	if a.Type != UNKNOWN && a.Type != UNDEFINED && getType(v) != a.Type {
+		return nil, e.Error(at.Line, at.Column, at.Lexeme, e.RUNTIME, fmt.Sprintf("%s holds %s, found: %s", a.Name, typeToString(a.Type), describeValue(v)))
+	}
+	release := changing()
+	(*a.Values)[a.Index] = v
+	elements := *a.Values
+	release()
+
+	// the address does not keep if the element is of a slice or of an array, whose elements live elsewhere
+	touched(Slice{Values: a.Values})
+	touched(Array{Values: elements})
+	return v, nil
+}
+
+// length is how many elements the collection of the address has now
+func (a Address) length() int {
+	defer viewing()()
+	return len(*a.Values)
+}
+
+// deref follows a pointer, nil or anything that is not a pointer cannot be followed
+func deref(at l.Token, v any) (Address, error) {
+	switch a := v.(type) {
+	case Address:
+		return a, nil
+	case nil:
+		return Address{}, e.Error(at.Line, at.Column, at.Lexeme, e.RUNTIME, "nil pointer dereference")
+	default:
+		return Address{}, e.Error(at.Line, at.Column, at.Lexeme, e.RUNTIME, fmt.Sprintf("cannot dereference %v, it is not a pointer", v))
+	}
+}
+
+// `&x` takes the address and `*p` reads what it points to
+func (s *Scope) PointerEval(p Pointer) (any, error) {
+	if p.Operator.Type == l.AND_BITWISE {
+		return s.address(p.Operator, p.Right)
+	}
+
+	v, err := s.evaluate(p.Right)
+	if err != nil {
+		return nil, err
+	}
+	a, err := deref(p.Operator, v)
+	if err != nil {
+		return nil, err
+	}
+	return a.load(p.Operator)
+}
+
+// address finds where a variable, an element or a field lives. A `?.` that found nil
+// on the way gives a nil pointer
+func (s *Scope) address(at l.Token, x Expr) (any, error) {
+	switch t := x.(type) {
+	case Grouping:
+		return s.address(at, t.Expression)
+	case Identifier:
+		scope, slot := s.ancestor(t.Depth), t.Slot
+		v, found := scope.variable(slot)
+		if !found {
+			return nil, e.Error(t.Name.Line, t.Name.Column, t.Name.Lexeme, e.RUNTIME, "variable created but not defined")
+		}
+		// a parameter that got `x!` is x itself
+		for v.Ref != nil {
+			scope, slot = v.Ref.Scope, v.Ref.Slot
+			v, _ = scope.variable(slot)
+		}
+		return Address{Scope: scope, Slot: slot, Type: v.Type, Name: t.Name.Lexeme}, nil
+	case Access:
+		instance, name, err := s.instance(t)
+		if err != nil || instance == nil {
+			return nil, err
+		}
+		if _, isCall := t.Right.(Call); isCall {
+			return nil, e.Error(name.Line, name.Column, name.Lexeme, e.RUNTIME, "cannot take the address of a method call")
+		}
+		slot, err := instance.member(name, t.Inside, false)
+		if err != nil {
+			return nil, err
+		}
+		_, writeErr := instance.member(name, t.Inside, true)
+		v, _ := instance.Fields.variable(slot)
+		return Address{Scope: instance.Fields, Slot: slot, Type: v.Type, Name: spelled(t.Left) + "." + name.Lexeme, ReadOnly: writeErr != nil || s.mutable(t.Left) != nil}, nil
+	case PositionAccess:
+		collection, skipped, err := s.link(t.Expression)
+		if err != nil || skipped {
+			return nil, err
+		}
+		pos, err := s.evaluate(t.Pos)
+		if err != nil {
+			return nil, err
+		}
+
+		a := Address{ReadOnly: s.mutable(t.Expression) != nil || frozen(collection)}
+		switch c := collection.(type) {
+		case Array:
+			a.Values, a.Type = &c.Values, c.Type
+		case Slice:
+			a.Values, a.Type = c.Values, c.Type
+		default:
+			b := t.Bracket
+			return nil, e.Error(b.Line, b.Column, b.Lexeme, e.RUNTIME, fmt.Sprintf("cannot take the address of an element of %v", collection))
+		}
+		if a.Index, err = index(t.Bracket, pos, a.length()); err != nil {
+			return nil, err
+		}
+		a.Name = fmt.Sprintf("%s[%d]", spelled(t.Expression), a.Index)
+		return a, nil
+	case Pointer:
+		// `&*p` is p
+		if t.Operator.Type == l.STAR {
+			v, err := s.evaluate(t.Right)
+			if err != nil {
+				return nil, err
+			}
+			return deref(t.Operator, v)
+		}
+	}
+	return nil, e.Error(at.Line, at.Column, at.Lexeme, e.RUNTIME, fmt.Sprintf("cannot take the address of %v, only of variables, elements and fields", x))
+}
+
+// spelled writes a variable, a field or an element the way the code does, to name what a pointer points to
+func spelled(x Expr) string {
+	switch t := x.(type) {
+	case Identifier:
+		return t.Name.Lexeme
+	case Access:
+		if i, ok := t.Right.(Identifier); ok {
+			return spelled(t.Left) + t.Operator.Lexeme + i.Name.Lexeme
+		}
+	case PositionAccess:
+		return spelled(t.Expression) + "[" + spelled(t.Pos) + "]"
+	case Literal:
+		return fmt.Sprintf("%v", t.Value)
+	}
+	return fmt.Sprintf("%v", x)
+}
+
+// `*p = v` writes where p points, `*p += v` combines it with what is there
+func (s *Scope) PointerAssignEval(a PointerAssign) (any, error) {
+	v, err := s.evaluate(a.Value)
+	if err != nil {
+		return nil, err
+	}
+
+	p, err := s.evaluate(a.Target.Right)
+	if err != nil {
+		return nil, err
+	}
+	at := a.Target.Operator
+	target, err := deref(at, p)
+	if err != nil {
+		return nil, err
+	}
+
+	return target.store(at, a.Operator, v)
+}
@@ -1,6 +1,8 @@
 package parser
 
 import (
+	"sync/atomic"
+
 	"github.com/ToniLommez/Neon_Dream_Runner/pkg/lexer"
 )
 
@@ -10,9 +12,58 @@ type Program struct {
 	Tokens       []lexer.Token
 	TokensBuffer []lexer.Token
 	Main         Scope
+	Resolver     Resolver
 }
 
+// The builtins take the first slots of Main, the same ones the Resolver gives them. The registries
+// are made before anything runs, so tasks never race to make them
 func (p *Program) Init(isLive bool) {
 	p.IsLive = isLive
 	p.Main.Init()
+	p.Main.Events, p.Main.Aspects, p.Main.Reactor, p.Main.Tasks = &Events{}, &Aspects{}, NewReactor(), NewTasks()
+	p.Main.MainCalled = &atomic.Bool{}
+	p.Resolver.Live = isLive
+
+	for i, b := range builtins {
+		name := lexer.Token{Type: lexer.IDENTIFIER, Lexeme: b.Name}
+		p.Main.Define(LetStmt{Name: name, Type: FUNCTION, Initializer: Literal{b}, Slot: i}, b)
+	}
+}
+
+// When subscribes a Go function to an event of the script, it runs along the `when` handlers
+// in the order it was registered
+func (p *Program) When(event string, listener Listener) {
+	p.Main.events().When(event, listener)
+}
+
+// Trigger fires an event of the script from Go, as `trigger event args` would
+func (p *Program) Trigger(event string, args ...any) error {
+	return p.Main.Trigger(lexer.Token{Type: lexer.TRIGGER, Lexeme: event}, args)
+}
+
+// Wait blocks until every task started with `!>` ends, and returns the first error among them
+func (p *Program) Wait() error {
+	return p.Main.tasks().Wait()
+}
+
+// Running is how many tasks started with `!>` did not end yet
+func (p *Program) Running() int {
+	return p.Main.tasks().Running()
+}
+
+// Aspects lists the code injected by every pulse that ran, in the order they ran
+func (p *Program) Aspects() []Advice {
+	return p.Main.aspects().List()
+}
+
+// MainFunction finds the `fn main` of the script, which the program calls once the script ends.
+// A script that already called it does not get it called again
+func (p *Program) MainFunction() (Function, bool) {
+	slot, found := p.Resolver.Global("main")
+	if !found || slot >= len(p.Main.Values) || p.Main.MainCalled != nil && p.Main.MainCalled.Load() {
+		return Function{}, false
+	}
+
+	f, ok := p.Main.Values[slot].Value.(Function)
+	return f, ok && f.Arity() == 0
 }
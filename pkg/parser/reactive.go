@@ -0,0 +1,270 @@
+package parser
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+	"sync"
+	"sync/atomic"
+
+	e "github.com/ToniLommez/Neon_Dream_Runner/pkg/errutils"
+	l "github.com/ToniLommez/Neon_Dream_Runner/pkg/lexer"
+)
+
+// variable is a slot of a scope, what a reactive pulse watches
+type variable struct {
+	scope *Scope
+	slot  int
+}
+
+// watch is a reactive pulse, the variables and the elements of collections its condition read the
+// last time it was checked and if any of them was written since. dirty is read by the Tasks looking
+// for a deadlock, and tasks are the ones woken when it is set
+type watch struct {
+	reads    map[variable]bool
+	elements map[any]bool
+	tracking bool
+	dirty    atomic.Bool
+	tasks    *Tasks
+}
+
+// Reactor wakes the reactive pulses when a variable their condition read is written with Scope.Set,
+// or an element of a collection it read is written in place.
+// Reads are taken from anything that runs while a condition is checked, which at worst wakes a pulse
+// that finds its condition unchanged. A waiting pulse is a waiter of the Tasks
+type Reactor struct {
+	mu       sync.Mutex
+	watches  map[*watch]bool
+	watching atomic.Int32
+}
+
+func NewReactor() *Reactor {
+	return &Reactor{watches: make(map[*watch]bool)}
+}
+
+// watched are the reactors with a pulse, a collection does not know the program it belongs to,
+// so a write to its elements looks for the pulses that read them in all of them
+var watched struct {
+	sync.Mutex
+	reactors map[*Reactor]bool
+	pulses   atomic.Int32
+}
+
+// storage is where the elements of a collection live, which all its copies share, nil for anything else
+func storage(v any) any {
+	switch c := v.(type) {
+	case Slice:
+		return c.Values
+	case Map:
+		return c.entries
+	case Array:
+		if len(c.Values) > 0 {
+			return &c.Values[0]
+		}
+	}
+	return nil
+}
+
+func (s *Scope) reactor() *Reactor {
+	root := s.root()
+	if root.Reactor == nil {
+		root.Reactor = NewReactor()
+	}
+	return root.Reactor
+}
+
+// read tells the pulses checking their condition that it depends on the slot, and on the elements
+// of the collection it holds
+func (s *Scope) read(slot int, value any) {
+	r := s.root().Reactor
+	if r == nil || r.watching.Load() == 0 {
+		return
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	elements := storage(value)
+	for w := range r.watches {
+		if w.tracking {
+			w.reads[variable{s, slot}] = true
+			if elements != nil {
+				w.elements[elements] = true
+			}
+		}
+	}
+}
+
+// inspect tells the pulses checking their condition that it depends on the elements of a collection
+// that is not held by a variable, as the inner one of `xs[i][j]`
+func (s *Scope) inspect(collection any) {
+	r := s.root().Reactor
+	elements := storage(collection)
+	if r == nil || r.watching.Load() == 0 || elements == nil {
+		return
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	for w := range r.watches {
+		if w.tracking {
+			w.elements[elements] = true
+		}
+	}
+}
+
+// touched wakes the pulses whose condition read the elements of a collection that were just written
+func touched(collection any) {
+	if watched.pulses.Load() == 0 {
+		return
+	}
+	elements := storage(collection)
+	if elements == nil {
+		return
+	}
+
+	watched.Lock()
+	reactors := make([]*Reactor, 0, len(watched.reactors))
+	for r := range watched.reactors {
+		reactors = append(reactors, r)
+	}
+	watched.Unlock()
+
+	for _, r := range reactors {
+		r.mu.Lock()
+		for w := range r.watches {
+			if w.elements[elements] {
+				w.dirty.Store(true)
+				w.tasks.wake()
+			}
+		}
+		r.mu.Unlock()
+	}
+}
+
+// observed tells if a pulse is checking its condition, so the reads of variables are noted
+func (s *Scope) observed() bool {
+	r := s.root().Reactor
+	return r != nil && r.watching.Load() > 0
+}
+
+// wrote wakes the pulses whose condition read the slot
+func (s *Scope) wrote(slot int) {
+	r := s.root().Reactor
+	if r == nil || r.watching.Load() == 0 {
+		return
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	woken := false
+	for w := range r.watches {
+		if w.reads[variable{s, slot}] {
+			w.dirty.Store(true)
+			woken = true
+		}
+	}
+	if woken {
+		s.tasks().wake()
+	}
+}
+
+func (r *Reactor) watch(tasks *Tasks) *watch {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	w := &watch{tasks: tasks}
+	r.watches[w] = true
+	r.watching.Add(1)
+
+	watched.Lock()
+	defer watched.Unlock()
+	if watched.reactors == nil {
+		watched.reactors = make(map[*Reactor]bool)
+	}
+	watched.reactors[r] = true
+	watched.pulses.Add(1)
+	return w
+}
+
+func (r *Reactor) forget(w *watch) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	delete(r.watches, w)
+
+	watched.Lock()
+	defer watched.Unlock()
+	if r.watching.Add(-1) == 0 {
+		delete(watched.reactors, r)
+	}
+	watched.pulses.Add(-1)
+}
+
+// track starts collecting what the condition reads, forgetting the reads of the last check
+func (r *Reactor) track(w *watch, tracking bool) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if tracking {
+		w.reads, w.elements = make(map[variable]bool), make(map[any]bool)
+		w.dirty.Store(false)
+	}
+	w.tracking = tracking
+}
+
+// wait blocks until a variable read by the condition is written, it fails when nothing else is
+// running that could write it, or everything that runs is waiting too
+func (r *Reactor) wait(w *watch, tasks *Tasks, at l.Token) error {
+	r.mu.Lock()
+	reads := len(w.reads)
+	r.mu.Unlock()
+	if reads == 0 {
+		return e.Error(at.Line, at.Column, at.Lexeme, e.RUNTIME, "pulse condition reads no variable, it would never change")
+	}
+	if tasks.block(w.dirty.Load) {
+		return nil
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	names := make([]string, 0, len(w.reads))
+	for v := range w.reads {
+		if variable, found := v.scope.variable(v.slot); found {
+			names = append(names, variable.Name)
+		}
+	}
+	sort.Strings(names)
+	return e.Error(at.Line, at.Column, at.Lexeme, e.RUNTIME, fmt.Sprintf("pulse waits for a change of %s, but nothing else is running", strings.Join(names, ", ")))
+}
+
+// The body runs once the condition is checked, and again every time something it read changes
+func (s *Scope) ReactiveEval(p ReactiveStmt) (any, error) {
+	r := s.reactor()
+	w := r.watch(s.tasks())
+	defer r.forget(w)
+
+	var stuck error
+	for {
+		r.track(w, true)
+		c, err := s.evaluate(p.Condition)
+		r.track(w, false)
+		if err != nil {
+			return nil, err
+		}
+
+		b, err := Truthy(c)
+		if err != nil {
+			return nil, err
+		}
+		if b == p.Until {
+			return nil, nil
+		}
+
+		// nothing will wake it again, but what it waited for may already have happened
+		if stuck != nil {
+			return nil, stuck
+		}
+
+		if stop, err := s.iteration("", p.Body); err != nil || stop {
+			return nil, err
+		}
+		stuck = r.wait(w, s.tasks(), p.Keyword)
+	}
+}
@@ -0,0 +1,1192 @@
+package parser
+
+import (
+	"fmt"
+	"strings"
+
+	e "github.com/ToniLommez/Neon_Dream_Runner/pkg/errutils"
+	l "github.com/ToniLommez/Neon_Dream_Runner/pkg/lexer"
+)
+
+// Resolver runs between the parser and the evaluator, it binds every identifier
+// to how many scopes up it was declared (Depth) and its index there (Slot),
+// so the runtime never has to search variables by name
+type Resolver struct {
+	Live       bool
+	Warnings   []error
+	scopes     []*resolverScope
+	functions  []enclosing
+	moves      []*binding
+	joinPoints map[[2]int]map[string]bool // the labels of the join points of every fn, by where its name is
+	inside     []PulseStmt                // the `pulse inside` whose join point is checked once every fn was resolved
+	pulses     int                        // how many pulses were resolved, it numbers the next one
+}
+
+// enclosing is a function being resolved and the index of the scope of its parameters
+type enclosing struct {
+	Name  l.Token
+	Scope int
+}
+
+const (
+	bindVariable = iota
+	bindParameter
+	bindFunction
+	bindBuiltin
+)
+
+// Type is what the declaration tells about the value, UNKNOWN when it tells nothing, Object is the
+// obj or trait of an OBJECT value and Declaration is the statement of a function, obj or trait.
+// Moved is the `'` that surely gave the value away, empty when it may still be there
+type binding struct {
+	Name        l.Token
+	Kind        int
+	Slot        int
+	Used        bool
+	Type        int
+	Nullable    bool
+	Object      string
+	Declaration Stmt
+	Moved       l.Token
+	Mutable     bool
+}
+
+type resolverScope struct {
+	names   map[string]*binding
+	all     []*binding
+	hoisted map[[2]int]*binding // functions declared before the statements of the scope run
+	moves   int                 // how many moves were made before the scope, the ones after it may not run
+}
+
+func (r *Resolver) Resolve(statements []Stmt) ([]Stmt, error) {
+	r.Warnings = nil
+
+	// The global scope is never closed, so the REPL keeps its declarations
+	if len(r.scopes) == 0 {
+		r.begin()
+		for _, n := range builtins {
+			b, _ := r.declare(l.Token{Type: l.IDENTIFIER, Lexeme: n.Name}, bindBuiltin)
+			b.Type = FUNCTION
+		}
+	}
+
+	if err := r.hoist(statements); err != nil {
+		return nil, err
+	}
+
+	r.inside = nil
+	statements, err := r.statements(statements)
+	if err != nil {
+		return nil, err
+	}
+	return statements, r.labels()
+}
+
+// labels checks that every `pulse inside` names a join point of its target, the bodies of the functions
+// are all resolved by now, so a pulse may come before the fn it goes into
+func (r *Resolver) labels() error {
+	for _, p := range r.inside {
+		if !r.joinPoints[[2]int{p.Function.Line, p.Function.Column}][p.Label.Lexeme] {
+			at := p.Label
+			return e.Error(at.Line, at.Column, at.Lexeme, e.RESOLVER, fmt.Sprintf("%s has no join point @%s", p.Function.Lexeme, at.Lexeme))
+		}
+	}
+	return nil
+}
+
+// Global returns the slot of a variable declared in the global scope
+func (r *Resolver) Global(name string) (int, bool) {
+	if len(r.scopes) == 0 {
+		return 0, false
+	}
+
+	b, found := r.scopes[0].names[name]
+	if !found {
+		return 0, false
+	}
+	return b.Slot, true
+}
+
+func (r *Resolver) begin() {
+	r.scopes = append(r.scopes, &resolverScope{names: make(map[string]*binding), hoisted: make(map[[2]int]*binding), moves: len(r.moves)})
+}
+
+// end closes the innermost scope and returns how many slots it needs
+func (r *Resolver) end() int {
+	scope := r.scopes[len(r.scopes)-1]
+	r.scopes = r.scopes[:len(r.scopes)-1]
+	r.forget(scope.moves)
+
+	for _, b := range scope.all {
+		if b.Kind == bindVariable && !b.Used && !strings.HasPrefix(b.Name.Lexeme, "_") {
+			r.Warnings = append(r.Warnings, e.Error(b.Name.Line, b.Name.Column, b.Name.Lexeme, e.WARNING, fmt.Sprintf("%s declared but never used", b.Name.Lexeme)))
+		}
+	}
+
+	return len(scope.all)
+}
+
+func (r *Resolver) declare(name l.Token, kind int) (*binding, error) {
+	scope := r.scopes[len(r.scopes)-1]
+
+	// Variables may shadow variables and builtins, but parameters and functions are unique in their scope.
+	// The REPL declares everything again in its global scope, so it may redo any of them
+	if old, found := scope.names[name.Lexeme]; found && old.Kind != bindBuiltin && !(r.Live && len(r.scopes) == 1) {
+		if old.Kind != bindVariable || kind != bindVariable {
+			return nil, e.Error(name.Line, name.Column, name.Lexeme, e.RESOLVER, fmt.Sprintf("%s is already declared in this scope", name.Lexeme))
+		}
+		if !strings.HasPrefix(name.Lexeme, "_") {
+			r.Warnings = append(r.Warnings, e.Error(name.Line, name.Column, name.Lexeme, e.WARNING, fmt.Sprintf("%s is already declared in this scope at line %d, this declaration shadows it", name.Lexeme, old.Name.Line)))
+		}
+	}
+
+	b := &binding{Name: name, Kind: kind, Slot: len(scope.all)}
+	scope.names[name.Lexeme] = b
+	scope.all = append(scope.all, b)
+	return b, nil
+}
+
+// Hoisted tells if a statement is declared before the others of its scope, the backends define
+// these when the scope is entered
+func Hoisted(stmt Stmt) bool {
+	switch stmt.(type) {
+	case FnStmt, ObjStmt, TraitStmt:
+		return true
+	default:
+		return false
+	}
+}
+
+// hoist declares the functions, objects and traits of a scope before its statements, so they can use each other
+func (r *Resolver) hoist(statements []Stmt) error {
+	scope := r.scopes[len(r.scopes)-1]
+	for _, stmt := range statements {
+		var name l.Token
+		switch x := stmt.(type) {
+		case FnStmt:
+			name = x.Name
+		case ObjStmt:
+			name = x.Name
+		case TraitStmt:
+			name = x.Name
+		default:
+			continue
+		}
+
+		b, err := r.declare(name, bindFunction)
+		if err != nil {
+			return err
+		}
+		b.Declaration = stmt
+		scope.hoisted[[2]int{name.Line, name.Column}] = b
+	}
+	return nil
+}
+
+// lookup returns the depth and slot of a name, marking it as used when read
+func (r *Resolver) lookup(name l.Token, read bool) (int, int, error) {
+	for i := len(r.scopes) - 1; i >= 0; i-- {
+		if b, found := r.scopes[i].names[name.Lexeme]; found {
+			if read && b.Moved.Lexeme != "" {
+				return 0, 0, e.Error(name.Line, name.Column, name.Lexeme, e.RESOLVER, fmt.Sprintf("%s is used after being moved at line %d", name.Lexeme, b.Moved.Line))
+			}
+			b.Used = b.Used || read
+			return len(r.scopes) - 1 - i, b.Slot, nil
+		}
+	}
+	return 0, 0, e.Error(name.Line, name.Column, name.Lexeme, e.RESOLVER, fmt.Sprintf("%s is used before being declared", name.Lexeme))
+}
+
+// move marks a variable as given away, only variables and parameters own their values
+func (r *Resolver) move(m Move) (Expr, error) {
+	name := m.Variable.Name
+	if b := r.find(name.Lexeme); b != nil && b.Kind != bindVariable && b.Kind != bindParameter {
+		return nil, e.Error(name.Line, name.Column, name.Lexeme, e.RESOLVER, fmt.Sprintf("only variables can be moved, %s is not one", name.Lexeme))
+	}
+
+	var err error
+	if m.Variable.Depth, m.Variable.Slot, err = r.lookup(name, true); err != nil {
+		return nil, err
+	}
+	b := r.find(name.Lexeme)
+	b.Moved = m.Quote
+	r.moves = append(r.moves, b)
+	return m, nil
+}
+
+// borrow lends a variable to a call, like a move only variables and parameters can be lent
+func (r *Resolver) borrow(x Borrow) (Expr, error) {
+	name := x.Variable.Name
+	if b := r.find(name.Lexeme); b != nil && b.Kind != bindVariable && b.Kind != bindParameter {
+		return nil, e.Error(name.Line, name.Column, name.Lexeme, e.RESOLVER, fmt.Sprintf("only variables can be passed with !, %s is not one", name.Lexeme))
+	}
+
+	var err error
+	x.Variable.Depth, x.Variable.Slot, err = r.lookup(name, true)
+	return x, err
+}
+
+// forget the moves made since a point, they were in code that may not run
+func (r *Resolver) forget(since int) {
+	for _, b := range r.moves[since:] {
+		b.Moved = l.Token{}
+	}
+	r.moves = r.moves[:since]
+}
+
+// maybe resolves a part of an expression that may not run, so its moves are not sure to happen
+func (r *Resolver) maybe(x Expr) (Expr, error) {
+	if x == nil {
+		return nil, nil
+	}
+	since := len(r.moves)
+	x, err := r.expr(x)
+	r.forget(since)
+	return x, err
+}
+
+func (r *Resolver) statements(statements []Stmt) ([]Stmt, error) {
+	resolved := make([]Stmt, len(statements))
+	for i, stmt := range statements {
+		s, err := r.stmt(stmt)
+		if err != nil {
+			return nil, err
+		}
+		resolved[i] = s
+	}
+	return resolved, nil
+}
+
+// access resolves the left side, the right side is a member name and only the arguments of a method call are variables
+func (r *Resolver) access(a Access) (Access, error) {
+	var err error
+	if a.Left, err = r.expr(a.Left); err != nil {
+		return a, err
+	}
+
+	if c, ok := a.Right.(Call); ok {
+		c.Args, err = r.args(c.Args)
+		a.Right = c
+	}
+	return a, err
+}
+
+// args resolves the arguments of a call, the only place where `x!` lends x
+func (r *Resolver) args(exprs []Expr) ([]Expr, error) {
+	resolved := make([]Expr, len(exprs))
+	for i, x := range exprs {
+		var err error
+		if b, ok := x.(Borrow); ok {
+			resolved[i], err = r.borrow(b)
+		} else {
+			resolved[i], err = r.expr(x)
+		}
+		if err != nil {
+			return nil, err
+		}
+	}
+	return resolved, nil
+}
+
+func (r *Resolver) exprs(exprs []Expr) ([]Expr, error) {
+	resolved := make([]Expr, len(exprs))
+	for i, x := range exprs {
+		v, err := r.expr(x)
+		if err != nil {
+			return nil, err
+		}
+		resolved[i] = v
+	}
+	return resolved, nil
+}
+
+func (r *Resolver) stmt(stmt Stmt) (Stmt, error) {
+	var err error
+
+	switch s := stmt.(type) {
+	case LetStmt:
+		return r.let(s)
+	case DestructureStmt:
+		if s.Initializer, err = r.expr(s.Initializer); err != nil {
+			return nil, err
+		}
+		lets := make([]LetStmt, len(s.Lets))
+		for i, let := range s.Lets {
+			b, err := r.declare(let.Name, bindVariable)
+			if err != nil {
+				return nil, err
+			}
+			let.Slot, b.Mutable = b.Slot, let.Mutable
+			lets[i] = let
+		}
+		s.Lets = lets
+		return s, nil
+	case FnStmt:
+		b, err := r.named(s.Name, s)
+		if err != nil {
+			return nil, err
+		}
+		b.Type = FUNCTION
+		s.Slot = b.Slot
+		return r.function(s)
+	case ObjStmt:
+		b, err := r.named(s.Name, s)
+		if err != nil {
+			return nil, err
+		}
+		s.Slot = b.Slot
+		return r.object(s)
+	case TraitStmt:
+		b, err := r.named(s.Name, s)
+		if err != nil {
+			return nil, err
+		}
+		s.Slot = b.Slot
+		return s, nil
+	case WhenStmt:
+		s.Handler, err = r.function(s.Handler)
+		return s, err
+	case TriggerStmt:
+		s.Args, err = r.args(s.Args)
+		return s, err
+	case SpawnStmt:
+		s.Task, err = r.expr(s.Task)
+		return s, err
+	case ErrorStmt:
+		return r.raise(s)
+	case PulseStmt:
+		return r.pulse(s)
+	case JoinPoint:
+		if len(r.functions) == 0 {
+			return nil, e.Error(s.Label.Line, s.Label.Column, s.Label.Lexeme, e.RESOLVER, fmt.Sprintf("join point @%s outside a function", s.Label.Lexeme))
+		}
+		f := r.functions[len(r.functions)-1]
+		s.Function, s.Depth = f.Name, len(r.scopes)-1-f.Scope
+
+		if r.joinPoints == nil {
+			r.joinPoints = make(map[[2]int]map[string]bool)
+		}
+		at := [2]int{f.Name.Line, f.Name.Column}
+		if r.joinPoints[at] == nil {
+			r.joinPoints[at] = make(map[string]bool)
+		}
+		r.joinPoints[at][s.Label.Lexeme] = true
+		return s, nil
+	case ReturnStmt:
+		if s.Value != nil {
+			if s.Value, err = r.expr(s.Value); err != nil {
+				return nil, err
+			}
+		}
+		return s, nil
+	case PutStmt:
+		s.Value, err = r.expr(s.Value)
+		return s, err
+	case ExprStmt:
+		s.Expr, err = r.expr(s.Expr)
+		return s, err
+	default:
+		return r.expr(stmt)
+	}
+}
+
+// raise tells apart `error kind` from `error variable` by what is declared
+func (r *Resolver) raise(x ErrorStmt) (Stmt, error) {
+	var err error
+
+	if x.Kind.Lexeme != "" && x.Message == nil && r.find(x.Kind.Lexeme) != nil {
+		x.Value, x.Kind = Identifier{Name: x.Kind}, l.Token{}
+	}
+	if x.Message, x.Value, err = r.pair(x.Message, x.Value); err != nil {
+		return nil, err
+	}
+	x.Results, err = r.handler(x.Results)
+	return x, err
+}
+
+// handler resolves what runs when something fails, in a scope of its own where `err` is the error
+func (r *Resolver) handler(x Expr) (Expr, error) {
+	if x == nil {
+		return nil, nil
+	}
+
+	r.begin()
+	b, err := r.declare(l.Token{Type: l.IDENTIFIER, Lexeme: errName}, bindParameter)
+	if err != nil {
+		return nil, err
+	}
+	b.Type = ERR
+	x, err = r.expr(x)
+	r.end()
+	return x, err
+}
+
+// named returns the binding of a function, obj or trait, which was declared already when hoisted
+func (r *Resolver) named(name l.Token, declaration Stmt) (*binding, error) {
+	scope := r.scopes[len(r.scopes)-1]
+	if b, found := scope.hoisted[[2]int{name.Line, name.Column}]; found {
+		return b, nil
+	}
+
+	b, err := r.declare(name, bindFunction)
+	if err != nil {
+		return nil, err
+	}
+	b.Declaration = declaration
+	return b, nil
+}
+
+// arguments checks the objs given to trait parameters, when the function being called is known
+func (r *Resolver) arguments(c Call) error {
+	callee, ok := c.Callee.(Identifier)
+	if !ok {
+		return nil
+	}
+
+	b := r.find(callee.Name.Lexeme)
+	if b == nil {
+		return nil
+	}
+	if b.Kind == bindBuiltin && builtins[b.Slot].Changes && len(c.Args) > 0 {
+		return r.changes(callee.Name, c.Args[0])
+	}
+	f, ok := b.Declaration.(FnStmt)
+	if !ok {
+		return nil
+	}
+
+	for i, param := range f.Params {
+		if i < len(c.Args) {
+			if err := r.conformance(c.Token, param.Object, c.Args[i]); err != nil {
+				return err
+			}
+			if err := r.lends(f, param, c.Args[i]); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// lends checks that what a call passes with ! to a parameter declared with ! may change
+func (r *Resolver) lends(f FnStmt, param Param, arg Expr) error {
+	x, ok := arg.(Borrow)
+	if !ok || !param.Mutable {
+		return nil
+	}
+	name := x.Variable.Name
+	if b := r.find(name.Lexeme); b != nil && !b.Mutable {
+		return e.Error(name.Line, name.Column, name.Lexeme, e.RESOLVER, fmt.Sprintf("%s is immutable, %s cannot change it through %s", name.Lexeme, f.Name.Lexeme, param.Name.Lexeme))
+	}
+	return nil
+}
+
+// changes checks that the collection a builtin changes in place is not held by an immutable variable
+func (r *Resolver) changes(builtin l.Token, arg Expr) error {
+	var name l.Token
+	switch x := arg.(type) {
+	case Grouping:
+		return r.changes(builtin, x.Expression)
+	case PositionAccess:
+		return r.changes(builtin, x.Expression)
+	case Identifier:
+		name = x.Name
+	case Borrow:
+		name = x.Variable.Name
+	default:
+		return nil
+	}
+
+	if b := r.find(name.Lexeme); b != nil && !b.Mutable {
+		return e.Error(name.Line, name.Column, name.Lexeme, e.RESOLVER, fmt.Sprintf("%s cannot change %s because it is immutable", builtin.Lexeme, name.Lexeme))
+	}
+	return nil
+}
+
+// find returns the binding of a name without marking it as used
+func (r *Resolver) find(name string) *binding {
+	for i := len(r.scopes) - 1; i >= 0; i-- {
+		if b, found := r.scopes[i].names[name]; found {
+			return b
+		}
+	}
+	return nil
+}
+
+// objectOf is the obj an expression is known to hold before running, if any
+func (r *Resolver) objectOf(x Expr) (ObjStmt, bool) {
+	var name string
+	switch v := x.(type) {
+	case ObjectLiteral:
+		name = v.Object.Name.Lexeme
+	case Identifier:
+		if b := r.find(v.Name.Lexeme); b != nil {
+			name = b.Object
+		}
+	}
+
+	if b := r.find(name); b != nil {
+		o, ok := b.Declaration.(ObjStmt)
+		return o, ok
+	}
+	return ObjStmt{}, false
+}
+
+// conformance reports before running an obj that does not have the methods of the trait it is given to
+func (r *Resolver) conformance(at l.Token, expected string, value Expr) error {
+	b := r.find(expected)
+	if expected == "" || b == nil {
+		return nil
+	}
+
+	t, isTrait := b.Declaration.(TraitStmt)
+	o, known := r.objectOf(value)
+	if !isTrait || !known {
+		return nil
+	}
+
+	if lacking := missing(o, t); len(lacking) > 0 {
+		return e.Error(at.Line, at.Column, at.Lexeme, e.RESOLVER, fmt.Sprintf("%s does not implement %s%s", o.Name.Lexeme, t.Name.Lexeme, lacks(lacking)))
+	}
+	return nil
+}
+
+func (r *Resolver) let(s LetStmt) (Stmt, error) {
+	var err error
+
+	// The initializer is resolved first, so `let x = x + 1` reads the outer x
+	if s.Initializer != nil {
+		if s.Initializer, err = r.expr(s.Initializer); err != nil {
+			return nil, err
+		}
+	}
+
+	s.Initializer = zeroOf(s.Initializer, s.Type)
+	if literal, ok := s.Initializer.(Literal); ok {
+		if _, fits := fitted(s.Type, literal.Value); !fits {
+			return nil, e.Error(s.Name.Line, s.Name.Column, s.Name.Lexeme, e.RESOLVER, fmt.Sprintf("%v does not fit in %s", literal.Value, typeToString(s.Type)))
+		}
+	}
+
+	if s.Named, err = r.annotated(s.Object, s.Name); err != nil {
+		return nil, err
+	}
+
+	b, err := r.declare(s.Name, bindVariable)
+	if err != nil {
+		return nil, err
+	}
+	s.Slot = b.Slot
+	b.Type, b.Nullable, b.Object, b.Mutable = s.Type, s.Nullable, s.Object, s.Mutable
+	if literal, ok := s.Initializer.(Literal); ok && s.Type == UNDEFINED {
+		b.Type = getType(literal.Value)
+	}
+	if o, ok := r.objectOf(s.Initializer); ok && s.Type == UNDEFINED {
+		b.Type, b.Object = OBJECT, o.Name.Lexeme
+	}
+	if err := r.conformance(s.Name, s.Object, s.Initializer); err != nil {
+		return nil, err
+	}
+
+	return s, nil
+}
+
+func (r *Resolver) function(f FnStmt) (FnStmt, error) {
+	var err error
+
+	r.begin()
+	r.functions = append(r.functions, enclosing{Name: f.Name, Scope: len(r.scopes) - 1})
+	defer func() { r.functions = r.functions[:len(r.functions)-1] }()
+
+	if f.Params, err = r.parameters(f.Params); err != nil {
+		return f, err
+	}
+
+	// A block body runs straight in the scope of the call, next to the parameters
+	if b, ok := f.Body.(Block); ok {
+		if err = r.hoist(b.Scope.Statements); err != nil {
+			return f, err
+		}
+		if b.Scope.Statements, err = r.statements(b.Scope.Statements); err != nil {
+			return f, err
+		}
+		f.Body = b
+	} else if f.Body, err = r.expr(f.Body); err != nil {
+		return f, err
+	}
+
+	f.Slots = r.end()
+	return f, nil
+}
+
+// parameters declares the parameters and gives them back with their obj or trait bound, as a new
+// slice, since the same parameters are also resolved in the scope of every pulse on the function
+func (r *Resolver) parameters(params []Param) ([]Param, error) {
+	resolved := make([]Param, len(params))
+	for i, param := range params {
+		var err error
+		if param.Named, err = r.annotated(param.Object, param.Name); err != nil {
+			return nil, err
+		}
+		b, err := r.declare(param.Name, bindParameter)
+		if err != nil {
+			return nil, err
+		}
+		b.Type, b.Nullable, b.Object, b.Mutable = param.Type, param.Nullable, param.Object, param.Mutable
+		resolved[i] = param
+	}
+	return resolved, nil
+}
+
+// annotated binds the obj or trait an annotation names, so checking a value against it reads a slot
+func (r *Resolver) annotated(object string, at l.Token) (Identifier, error) {
+	if object == "" {
+		return Identifier{}, nil
+	}
+	name := l.Token{Type: l.IDENTIFIER, Lexeme: object, Line: at.Line, Column: at.Column}
+	depth, slot, err := r.lookup(name, true)
+	return Identifier{Name: name, Depth: depth, Slot: slot}, err
+}
+
+// pulse resolves the injected code in a scope with the parameters of the target, the same
+// slots they have when the target runs. The target is known here, so a pulse may come before its fn
+func (r *Resolver) pulse(p PulseStmt) (Stmt, error) {
+	target, err := r.expr(p.Target)
+	if err != nil {
+		return nil, err
+	}
+	p.Target = target.(Identifier)
+
+	name := p.Target.Name
+	f, ok := r.find(name.Lexeme).Declaration.(FnStmt)
+	if !ok {
+		return nil, e.Error(name.Line, name.Column, name.Lexeme, e.RESOLVER, fmt.Sprintf("pulse expects a function declared with fn, found %s", name.Lexeme))
+	}
+	p.Function, p.Params = f.Name, len(f.Params)
+	r.pulses++
+	p.Number = r.pulses
+	if p.When.Type == l.INSIDE {
+		r.inside = append(r.inside, p)
+	}
+
+	r.begin()
+	if _, err = r.parameters(f.Params); err != nil {
+		return nil, err
+	}
+	p.Body, err = r.expr(p.Body)
+	r.end()
+
+	return p, err
+}
+
+// object resolves the scope of an instance, where `this`, the fields and the methods live,
+// the handlers of its events run there too
+func (r *Resolver) object(o ObjStmt) (ObjStmt, error) {
+	var err error
+
+	r.begin()
+	this, _ := r.declare(l.Token{Type: l.THIS, Lexeme: "this", Line: o.Name.Line, Column: o.Name.Column}, bindParameter)
+	this.Type = OBJECT
+
+	for i, f := range o.Fields {
+		if o.Fields[i].Named, err = r.annotated(f.Object, f.Name); err != nil {
+			return o, err
+		}
+		b, err := r.declare(f.Name, bindParameter)
+		if err != nil {
+			return o, err
+		}
+		b.Type, b.Nullable, b.Mutable = f.Type, f.Nullable, f.Mutable
+		o.Fields[i].Slot = b.Slot
+	}
+
+	methods := make([]Stmt, len(o.Methods))
+	for i, m := range o.Methods {
+		methods[i] = m
+	}
+	if err = r.hoist(methods); err != nil {
+		return o, err
+	}
+
+	for i, f := range o.Fields {
+		if f.Initializer != nil {
+			if o.Fields[i].Initializer, err = r.expr(f.Initializer); err != nil {
+				return o, err
+			}
+		}
+	}
+
+	scope := r.scopes[len(r.scopes)-1]
+	for i, m := range o.Methods {
+		m.Slot = scope.hoisted[[2]int{m.Name.Line, m.Name.Column}].Slot
+		if o.Methods[i], err = r.function(m); err != nil {
+			return o, err
+		}
+	}
+
+	for i, h := range o.Handlers {
+		if o.Handlers[i].Handler, err = r.function(h.Handler); err != nil {
+			return o, err
+		}
+	}
+
+	o.Slots = r.end()
+	return o, nil
+}
+
+func (r *Resolver) block(b Block) (Block, error) {
+	var err error
+
+	r.begin()
+	if err = r.hoist(b.Scope.Statements); err != nil {
+		return b, err
+	}
+	if b.Scope.Statements, err = r.statements(b.Scope.Statements); err != nil {
+		return b, err
+	}
+	b.Slots = r.end()
+
+	return b, nil
+}
+
+func (r *Resolver) expr(expr Expr) (Expr, error) {
+	var err error
+
+	switch x := expr.(type) {
+	case ReactiveStmt:
+		x.Condition, x.Body, err = r.pair(x.Condition, x.Body)
+		return x, err
+	case WhileStmt:
+		if x.Condition, err = r.expr(x.Condition); err != nil {
+			return nil, err
+		}
+		x.Body, x.Increment, err = r.pair(x.Body, x.Increment)
+		return x, err
+	case DoStmt:
+		x.Body, x.Condition, err = r.pair(x.Body, x.Condition)
+		return x, err
+	case LoopStmt:
+		x.Times, x.Body, err = r.pair(x.Times, x.Body)
+		return x, err
+	case ForInStmt:
+		return r.forIn(x)
+	case IfStmt:
+		if x.Condition, err = r.expr(x.Condition); err != nil {
+			return nil, err
+		}
+		if x.Then, err = r.expr(x.Then); err != nil {
+			return nil, err
+		}
+		if x.Else != nil {
+			x.Else, err = r.expr(x.Else)
+		}
+		return x, err
+	case Block:
+		return r.block(x)
+	case Sequence:
+		x.Left, x.Right, err = r.pair(x.Left, x.Right)
+		return x, err
+	case Assign:
+		if x.Value, err = r.expr(x.Value); err != nil {
+			return nil, err
+		}
+		if b := r.find(x.Target.Lexeme); b != nil {
+			x.Value = zeroOf(x.Value, b.Type)
+		}
+		if x.Depth, x.Slot, err = r.lookup(x.Target, x.Operator.Type != l.ASSIGN); err != nil {
+			return nil, err
+		}
+		// a moved variable owns again what is assigned to it
+		if b := r.find(x.Target.Lexeme); b != nil {
+			b.Moved = l.Token{}
+		}
+		return x, nil
+	case PositionAssign:
+		if x.Value, err = r.expr(x.Value); err != nil {
+			return nil, err
+		}
+		target, err := r.expr(x.Target)
+		if err != nil {
+			return nil, err
+		}
+		x.Target = target.(PositionAccess)
+		return x, nil
+	case Pipeline:
+		x.Left, x.Right, err = r.pair(x.Left, x.Right)
+		return x, err
+	case Ternary:
+		if x.Expression, err = r.expr(x.Expression); err != nil {
+			return nil, err
+		}
+		if x.True, err = r.maybe(x.True); err != nil {
+			return nil, err
+		}
+		x.False, err = r.maybe(x.False)
+		return x, err
+	case Range:
+		if x.Left, x.Right, err = r.pair(x.Left, x.Right); err != nil {
+			return nil, err
+		}
+		if x.Step != nil {
+			x.Step, err = r.expr(x.Step)
+		}
+		return x, err
+	case Membership:
+		x.Left, x.Right, err = r.pair(x.Left, x.Right)
+		return x, err
+	case Logic:
+		if x.Left, err = r.expr(x.Left); err != nil {
+			return nil, err
+		}
+		x.Right, err = r.maybe(x.Right)
+		return x, err
+	case Equality:
+		x.Left, x.Right, err = r.pair(x.Left, x.Right)
+		return x, err
+	case Comparison:
+		x.Left, x.Right, err = r.pair(x.Left, x.Right)
+		return x, err
+	case Bitshift:
+		x.Left, x.Right, err = r.pair(x.Left, x.Right)
+		return x, err
+	case Bitwise:
+		x.Left, x.Right, err = r.pair(x.Left, x.Right)
+		return x, err
+	case Term:
+		x.Left, x.Right, err = r.pair(x.Left, x.Right)
+		return x, err
+	case Factor:
+		x.Left, x.Right, err = r.pair(x.Left, x.Right)
+		return x, err
+	case Power:
+		x.Left, x.Right, err = r.pair(x.Left, x.Right)
+		return x, err
+	case Increment:
+		x.Expression, err = r.expr(x.Expression)
+		return x, err
+	case Pointer:
+		if i, ok := x.Right.(Identifier); ok && x.Operator.Type == l.AND_BITWISE {
+			if b := r.find(i.Name.Lexeme); b != nil && b.Kind != bindVariable && b.Kind != bindParameter {
+				return nil, e.Error(i.Name.Line, i.Name.Column, i.Name.Lexeme, e.RESOLVER, fmt.Sprintf("only variables have an address, %s is not one", i.Name.Lexeme))
+			}
+		}
+		x.Right, err = r.expr(x.Right)
+		return x, err
+	case PointerAssign:
+		if x.Value, err = r.expr(x.Value); err != nil {
+			return nil, err
+		}
+		x.Target.Right, err = r.expr(x.Target.Right)
+		return x, err
+	case Unary:
+		x.Right, err = r.expr(x.Right)
+		return x, err
+	case Access:
+		return r.access(x)
+	case AccessAssign:
+		if x.Target, err = r.access(x.Target); err != nil {
+			return nil, err
+		}
+		x.Value, err = r.expr(x.Value)
+		return x, err
+	case ObjectLiteral:
+		if x.Object.Depth, x.Object.Slot, err = r.lookup(x.Object.Name, true); err != nil {
+			return nil, err
+		}
+		x.Values, err = r.exprs(x.Values)
+		return x, err
+	case PositionAccess:
+		x.Expression, x.Pos, err = r.pair(x.Expression, x.Pos)
+		return x, err
+	case Elvis:
+		if x.Left, err = r.expr(x.Left); err != nil {
+			return nil, err
+		}
+		x.Right, err = r.maybe(x.Right)
+		if x.ReturnZero {
+			x.Type = r.declared(x.Left)
+		}
+		return x, err
+	case Check:
+		if x.Left, err = r.expr(x.Left); err != nil {
+			return nil, err
+		}
+		if x.Right, err = r.handler(x.Right); err != nil {
+			return nil, err
+		}
+		x.Handler, err = r.handler(x.Handler)
+		return x, err
+	case ErrLiteral:
+		if x.Message != nil {
+			x.Message, err = r.expr(x.Message)
+		}
+		return x, err
+	case Cast:
+		x.Left, err = r.expr(x.Left)
+		return x, err
+	case Call:
+		if x.Callee, err = r.expr(x.Callee); err != nil {
+			return nil, err
+		}
+		if x.Args, err = r.args(x.Args); err != nil {
+			return nil, err
+		}
+		return x, r.arguments(x)
+	case Lambda:
+		x.Declaration, err = r.function(x.Declaration)
+		return x, err
+	case Multiple:
+		x.Values, err = r.exprs(x.Values)
+		return x, err
+	case Identifier:
+		x.Depth, x.Slot, err = r.lookup(x.Name, true)
+		return x, err
+	case Move:
+		return r.move(x)
+	case Borrow:
+		return nil, e.Error(x.Bang.Line, x.Bang.Column, x.Bang.Lexeme, e.RESOLVER, fmt.Sprintf("%s! is only allowed as the argument of a call", x.Variable.Name.Lexeme))
+	case ArrayLiteral:
+		if x.Size, err = r.expr(x.Size); err != nil {
+			return nil, err
+		}
+		x.Values, err = r.exprs(x.Values)
+		return x, err
+	case SliceLiteral:
+		x.Values, err = r.exprs(x.Values)
+		return x, err
+	case Send:
+		x.Channel, x.Value, err = r.pair(x.Channel, x.Value)
+		return x, err
+	case ChannelLiteral:
+		_, x.Size, err = r.pair(nil, x.Size)
+		return x, err
+	case MapLiteral:
+		if x.Keys, err = r.exprs(x.Keys); err != nil {
+			return nil, err
+		}
+		x.Values, err = r.exprs(x.Values)
+		return x, err
+	case Grouping:
+		x.Expression, err = r.expr(x.Expression)
+		return x, err
+	case Case:
+		return r.caseExpr(x)
+	case ExprStmt, PutStmt, LetStmt, DestructureStmt, FnStmt, ReturnStmt, TriggerStmt, PulseStmt, JoinPoint, SpawnStmt, ErrorStmt:
+		return r.stmt(x)
+	default:
+		return expr, nil
+	}
+}
+
+// The loop variables live in a scope around the body, made again for every iteration
+func (r *Resolver) forIn(f ForInStmt) (Expr, error) {
+	var err error
+
+	if f.Collection, err = r.expr(f.Collection); err != nil {
+		return nil, err
+	}
+
+	r.begin()
+	vars := make([]LetStmt, len(f.Vars))
+	for i, v := range f.Vars {
+		b, err := r.declare(v.Name, bindVariable)
+		if err != nil {
+			return nil, err
+		}
+		b.Type, b.Nullable = v.Type, v.Nullable
+		v.Slot = b.Slot
+		vars[i] = v
+	}
+	f.Vars = vars
+
+	if f.Body, err = r.expr(f.Body); err != nil {
+		return nil, err
+	}
+	f.Slots = r.end()
+
+	return f, nil
+}
+
+// Every arm gets its own scope for the bindings of its patterns
+func (r *Resolver) caseExpr(c Case) (Expr, error) {
+	var err error
+
+	if c.Subject, err = r.expr(c.Subject); err != nil {
+		return nil, err
+	}
+
+	arms := make([]Arm, len(c.Arms))
+	for i, arm := range c.Arms {
+		r.begin()
+		patterns := make([]Expr, len(arm.Patterns))
+		for j, pattern := range arm.Patterns {
+			if patterns[j], err = r.pattern(pattern); err != nil {
+				return nil, err
+			}
+		}
+		arm.Patterns = patterns
+		if arm.Guard, arm.Body, err = r.pair(arm.Guard, arm.Body); err != nil {
+			return nil, err
+		}
+		arm.Slots = r.end()
+		arms[i] = arm
+	}
+	c.Arms = arms
+
+	if c.Else != nil {
+		if c.Else, err = r.expr(c.Else); err != nil {
+			return nil, err
+		}
+	} else if tp, nullable, known := r.typeOf(c.Subject); known && !exhaustive(c.Arms, tp, nullable) {
+		k := c.Keyword
+		r.Warnings = append(r.Warnings, e.Error(k.Line, k.Column, k.Lexeme, e.WARNING, fmt.Sprintf("case over %s is not exhaustive, add an else arm", typeToString(tp))))
+	}
+
+	return c, nil
+}
+
+func (r *Resolver) pattern(pattern Expr) (Expr, error) {
+	var err error
+
+	switch x := pattern.(type) {
+	case Binding:
+		if x.Name.Lexeme == "_" {
+			x.Slot = -1
+			return x, nil
+		}
+
+		// the alternatives of an arm share their bindings, `of [x] | [_, x]`
+		if b, found := r.scopes[len(r.scopes)-1].names[x.Name.Lexeme]; found {
+			x.Slot = b.Slot
+			return x, nil
+		}
+
+		b, err := r.declare(x.Name, bindVariable)
+		if err != nil {
+			return nil, err
+		}
+		x.Slot = b.Slot
+		return x, nil
+	case ListPattern:
+		elements := make([]Expr, len(x.Elements))
+		for i, element := range x.Elements {
+			if elements[i], err = r.pattern(element); err != nil {
+				return nil, err
+			}
+		}
+		x.Elements = elements
+		if x.Rest != nil {
+			x.Rest, err = r.pattern(x.Rest)
+		}
+		return x, err
+	case TypePattern:
+		return x, nil
+	default:
+		return r.expr(pattern)
+	}
+}
+
+// typeOf tells the type of an expression when its declaration or literal makes it known
+func (r *Resolver) typeOf(expr Expr) (int, bool, bool) {
+	switch x := expr.(type) {
+	case Literal:
+		return getType(x.Value), false, x.Value != nil
+	case Grouping:
+		return r.typeOf(x.Expression)
+	case Identifier:
+		for i := len(r.scopes) - 1; i >= 0; i-- {
+			if b, found := r.scopes[i].names[x.Name.Lexeme]; found {
+				return b.Type, b.Nullable, b.Type != UNKNOWN && b.Type != UNDEFINED && b.Type != NIL
+			}
+		}
+	}
+	return UNKNOWN, false, false
+}
+
+// declared is the type an expression is declared with, the one of a variable, of a field of a
+// variable or what a function returns
+func (r *Resolver) declared(x Expr) int {
+	if a, ok := x.(Access); ok {
+		field, isField := a.Right.(Identifier)
+		if o, known := r.objectOf(a.Left); known && isField {
+			for _, f := range o.Fields {
+				if f.Name.Lexeme == field.Name.Lexeme {
+					return f.Type
+				}
+			}
+		}
+		return UNKNOWN
+	}
+	if c, ok := x.(Call); ok {
+		if callee, ok := c.Callee.(Identifier); ok {
+			if b := r.find(callee.Name.Lexeme); b != nil {
+				if f, ok := b.Declaration.(FnStmt); ok && len(f.Returns) == 1 {
+					return f.Returns[0]
+				}
+			}
+		}
+		return UNKNOWN
+	}
+
+	if tp, _, known := r.typeOf(x); known {
+		return tp
+	}
+	return UNKNOWN
+}
+
+// zeroOf gives a `?:` without a right side the type of where its value goes, when it is declared
+func zeroOf(x Expr, tp int) Expr {
+	if elvis, ok := x.(Elvis); ok && elvis.ReturnZero && tp != UNKNOWN && tp != UNDEFINED && tp != NIL {
+		elvis.Type = tp
+		return elvis
+	}
+	return x
+}
+
+// exhaustive tells if the arms without guards cover every value of the type
+func exhaustive(arms []Arm, tp int, nullable bool) bool {
+	var yes, no, null, all bool
+
+	for _, arm := range arms {
+		if arm.Guard != nil {
+			continue
+		}
+
+		for _, pattern := range arm.Patterns {
+			switch x := pattern.(type) {
+			case TypePattern:
+				all = all || x.Type == UNDEFINED || x.Type == tp
+				null = null || x.Nullable || x.Type == UNDEFINED
+			case Binding:
+				all, null = true, true
+			case Literal:
+				yes = yes || x.Value == true
+				no = no || x.Value == false
+				null = null || x.Value == nil
+			}
+		}
+	}
+
+	if tp == BOOL && yes && no {
+		all = true
+	}
+	return all && (null || !nullable)
+}
+
+// pair resolves two optional sides of an expression
+func (r *Resolver) pair(left Expr, right Expr) (Expr, Expr, error) {
+	var err error
+
+	if left != nil {
+		if left, err = r.expr(left); err != nil {
+			return nil, nil, err
+		}
+	}
+	if right != nil {
+		if right, err = r.expr(right); err != nil {
+			return nil, nil, err
+		}
+	}
+
+	return left, right, nil
+}
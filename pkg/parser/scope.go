@@ -2,108 +2,350 @@ package parser
 
 import (
 	"fmt"
+	"sync"
+	"sync/atomic"
+	"unsafe"
 
 	e "github.com/ToniLommez/Neon_Dream_Runner/pkg/errutils"
 	l "github.com/ToniLommez/Neon_Dream_Runner/pkg/lexer"
 )
 
+// Elem and Key are the element and key types of a slice, array or map and Object is the obj of an instance,
+// Trait is set when Object names a trait. Parts are the written types of what a tuple, a slice, an array or a
+// map holds and Size the one of an array.
+// Moved is the line where `x'` gave the value away, 0 while the variable still owns it.
+// Ref is set on a parameter declared with ! that got `x!`, reading and writing it goes to x
 type Variable struct {
+	Name        string
 	Value       any
 	Type        int
+	Elem        int
+	Key         int
+	Object      string
+	Parts       []annotation
+	Size        int
+	Trait       *TraitStmt
 	TypeDefined bool
 	Mutable     bool
 	Nullable    bool
 	Initialized bool
+	Moved       int
+	Ref         *Reference
 }
 
+// Values is indexed by the slots given by the Resolver, Events, Aspects, Reactor and Tasks are only
+// kept by the outermost scope
 type Scope struct {
 	Statements []Stmt
-	Values     map[string]Variable
+	Values     []Variable
 	Parent     *Scope // Cactus-Stack
+	Frame      *Frame // the call whose body runs here, nil for any other scope
+	Events     *Events
+	Aspects    *Aspects
+	Reactor    *Reactor
+	Tasks      *Tasks
+	MainCalled *atomic.Bool // kept by the outermost scope, set once the script calls its fn main
+	ended      bool         // its code finished, the pointers to its variables dangle
+}
+
+// locks guard the Values of the scopes, since tasks share them. A scope takes the lock its address
+// falls on, so it stays a plain value that the AST may copy. While no task runs there is nothing
+// to guard against, and reading and writing give no lock at all
+var locks [64]sync.RWMutex
+
+func (s *Scope) lock() *sync.RWMutex {
+	return &locks[uintptr(unsafe.Pointer(s))>>4%uintptr(len(locks))]
+}
+
+// reading takes the lock of the scope to read it, nil when no task runs
+func (s *Scope) reading() *sync.RWMutex {
+	if !concurrent() {
+		return nil
+	}
+	lock := s.lock()
+	lock.RLock()
+	return lock
+}
+
+// writing takes the lock of the scope to write it, nil when no task runs
+func (s *Scope) writing() *sync.RWMutex {
+	if !concurrent() {
+		return nil
+	}
+	lock := s.lock()
+	lock.Lock()
+	return lock
+}
+
+// End marks a scope whose code finished, a closure still reads its variables but a pointer does not
+func (s *Scope) End() {
+	if lock := s.writing(); lock != nil {
+		defer lock.Unlock()
+	}
+	s.ended = true
+}
+
+func (s *Scope) Ended() bool {
+	if lock := s.reading(); lock != nil {
+		defer lock.RUnlock()
+	}
+	return s.ended
 }
 
 func (s *Scope) Init() {
-	s.Values = make(map[string]Variable)
+	s.Values = make([]Variable, 0)
 }
 
-func (s *Scope) Define(l LetStmt, value any) (any, error) {
-	name := l.Name.Lexeme
-	tmp := s.Values[name]
+func (s *Scope) ancestor(depth int) *Scope {
+	scope := s
+	for i := 0; i < depth && scope != nil; i++ {
+		scope = scope.Parent
+	}
+	return scope
+}
 
-shadowing: // just some weird optimization
-	if _, found := s.Values[name]; found {
-		name = "§" + name
-		goto shadowing
-	} else {
-		s.Values[name] = tmp
+// variable reads a slot, found is false when the let that fills it did not run yet
+func (s *Scope) variable(slot int) (v Variable, found bool) {
+	if lock := s.reading(); lock != nil {
+		defer lock.RUnlock()
+	}
+	if slot >= len(s.Values) {
+		return Variable{}, false
+	}
+	return s.Values[slot], true
+}
+
+// variables is a copy of the slots, safe to go through while tasks change them
+func (s *Scope) variables() []Variable {
+	if lock := s.reading(); lock != nil {
+		defer lock.RUnlock()
+	}
+	return append([]Variable(nil), s.Values...)
+}
+
+func (s *Scope) Define(l LetStmt, value any) (any, error) {
+	trait := s.trait(l.Named)
+	if lock := s.writing(); lock != nil {
+		defer lock.Unlock()
+	}
+	for len(s.Values) <= l.Slot {
+		s.Values = append(s.Values, Variable{})
 	}
 
 	defined := l.Type != UNDEFINED && l.Type != UNKNOWN && l.Type != NIL
-	s.Values[l.Name.Lexeme] = Variable{Type: l.Type, Value: value, TypeDefined: defined, Mutable: l.Mutable, Nullable: l.Nullable, Initialized: l.Initializer != nil}
+	if l.Mutable {
+		value = thaw(value)
+	}
+	s.Values[l.Slot] = Variable{Name: l.Name.Lexeme, Type: l.Type, Elem: l.Elem, Key: l.Key, Object: l.Object, Parts: l.Parts, Size: l.Size, Trait: trait, Value: value, TypeDefined: defined, Mutable: l.Mutable, Nullable: l.Nullable, Initialized: l.Initializer != nil}
 
 	return value, nil
 }
 
 // return => type, value, isDefined, error
-func (s *Scope) Get(name l.Token) (int, any, bool, error) {
-	currentScope := s
-	for currentScope != nil {
-		value, found := currentScope.Values[name.Lexeme]
-		if found {
-			if !value.Initialized {
-				return value.Type, value.Value, false, e.Error(name.Line, name.Column, name.Lexeme, e.RUNTIME, "variable created but not defined")
-			}
-			return value.Type, value.Value, true, nil
-		}
-		currentScope = currentScope.Parent
+func (s *Scope) Get(name l.Token, depth int, slot int) (int, any, bool, error) {
+	scope := s.ancestor(depth)
+	if scope == nil {
+		return UNKNOWN, nil, false, e.Error(name.Line, name.Column, name.Lexeme, e.RUNTIME, "variable not found")
+	}
+
+	// only the fields it needs are taken, copying the whole Variable costs more than the rest of the read
+	lock := scope.reading()
+	found := slot < len(scope.Values)
+	var tp, moved int
+	var value any
+	var initialized, mutable bool
+	var ref *Reference
+	if found {
+		v := &scope.Values[slot]
+		tp, value, initialized, mutable, moved, ref = v.Type, v.Value, v.Initialized, v.Mutable, v.Moved, v.Ref
+	}
+	if lock != nil {
+		lock.RUnlock()
+	}
+
+	// declared by the Resolver, but its let did not run yet
+	if !found {
+		return UNKNOWN, nil, false, e.Error(name.Line, name.Column, name.Lexeme, e.RUNTIME, "variable created but not defined")
+	}
+
+	if !initialized {
+		return tp, value, false, e.Error(name.Line, name.Column, name.Lexeme, e.RUNTIME, "variable created but not defined")
+	}
+	if moved > 0 {
+		return tp, nil, false, e.Error(name.Line, name.Column, name.Lexeme, e.RUNTIME, fmt.Sprintf("%s was moved at line %d, it cannot be used anymore", name.Lexeme, moved))
+	}
+	if ref != nil {
+		return ref.Scope.Get(name, 0, ref.Slot)
+	}
+	scope.read(slot, value)
+	if !mutable {
+		value = freeze(value)
 	}
-	return UNKNOWN, nil, false, e.Error(name.Line, name.Column, name.Lexeme, e.RUNTIME, "variable not found")
+	return tp, value, true, nil
 }
 
-func (s *Scope) Set(target l.Token, newValue any) (any, error) {
-	currentScope := s
-	var v Variable
-	var found bool
+// Peek is Get for the vm when nothing can tell them apart: no task runs, no pulse watches the reads
+// and the variable holds a number, a bool or a string of its own. Anything else reports false and
+// is left to Get
+func (s *Scope) Peek(depth int, slot int) (any, bool) {
+	if concurrent() {
+		return nil, false
+	}
+	scope := s.ancestor(depth)
+	if scope == nil || slot >= len(scope.Values) || scope.observed() {
+		return nil, false
+	}
+	v := &scope.Values[slot]
+	if !v.Initialized || v.Moved > 0 || v.Ref != nil {
+		return nil, false
+	}
+	switch v.Value.(type) {
+	case int, float64, bool, string:
+		return v.Value, true
+	}
+	return nil, false
+}
 
-	// Walk up the scope until find
-	for currentScope != nil {
-		v, found = currentScope.Values[target.Lexeme]
-		if found {
-			break
-		}
-		currentScope = currentScope.Parent
+// Poke is Update for the vm when nothing can tell them apart: no task runs and an int variable with
+// no annotation beyond int takes or is combined with an int. Anything else, an overflow included,
+// reports false and is left to Update
+func (s *Scope) Poke(depth int, slot int, operator l.TokenType, n int) (any, bool) {
+	if concurrent() {
+		return nil, false
+	}
+	scope := s.ancestor(depth)
+	if scope == nil || slot >= len(scope.Values) {
+		return nil, false
+	}
+	v := &scope.Values[slot]
+	old, ok := v.Value.(int)
+	if !ok || !v.Initialized || !v.Mutable || v.Moved > 0 || v.Ref != nil || v.Type != INT || v.Elem != UNDEFINED || v.Object != "" || v.Trait != nil || v.Parts != nil {
+		return nil, false
+	}
+
+	switch operator {
+	case l.ASSIGN:
+	case l.ADD_ASSIGN:
+		n, ok = IntOp(l.PLUS, old, n)
+	case l.SUB_ASSIGN:
+		n, ok = IntOp(l.MINUS, old, n)
+	case l.MUL_ASSIGN:
+		n, ok = IntOp(l.STAR, old, n)
+	default:
+		return nil, false
 	}
+	if !ok {
+		return nil, false
+	}
+	v.Value = n
+	scope.wrote(slot)
+
+	return v.Value, true
+}
+
+func (s *Scope) Set(target l.Token, depth int, slot int, newValue any) (any, error) {
+	return s.Update(target, depth, slot, l.Token{Type: l.ASSIGN}, newValue)
+}
 
-	if currentScope == nil || !found {
+// Update combines the new value with the one the variable holds, as `+=` and the like do, and stores it.
+// Both happen while holding the lock of the variable, so tasks doing `x += 1` never lose one another's
+func (s *Scope) Update(target l.Token, depth int, slot int, operator l.Token, newValue any) (any, error) {
+	scope := s.ancestor(depth)
+	if scope == nil {
 		return nil, e.Error(target.Line, target.Column, target.Lexeme, e.RUNTIME, "variable not found")
 	}
 
+	value, ref, err := scope.assign(target, slot, operator, newValue)
+	if err != nil {
+		return nil, err
+	}
+	if ref != nil {
+		return ref.Scope.Update(target, 0, ref.Slot, operator, newValue)
+	}
+	scope.wrote(slot)
+
+	return value, nil
+}
+
+// assign checks and writes the slot while holding the lock, so no task writes it in between.
+// A parameter that is a reference gives it back instead, the write goes to the variable it points to
+func (s *Scope) assign(target l.Token, slot int, operator l.Token, newValue any) (any, *Reference, error) {
+	if lock := s.writing(); lock != nil {
+		defer lock.Unlock()
+	}
+	if slot >= len(s.Values) {
+		return nil, nil, e.Error(target.Line, target.Column, target.Lexeme, e.RUNTIME, "variable not found")
+	}
+	v := &s.Values[slot]
+	if v.Ref != nil {
+		return nil, v.Ref, nil
+	}
+
 	if v.Initialized && !v.Mutable {
-		return nil, e.Error(target.Line, target.Column, target.Lexeme, e.RUNTIME, "cannot assign because "+target.Lexeme+" is immutable")
+		return nil, nil, e.Error(target.Line, target.Column, target.Lexeme, e.RUNTIME, "cannot assign because "+target.Lexeme+" is immutable")
+	}
+
+	// a variable without a value yet takes the new one as it is
+	if v.Initialized && v.Moved == 0 {
+		var err error
+		if newValue, err = compound(operator, v.Value, newValue); err != nil {
+			return nil, nil, err
+		}
 	}
 
 	if newValue == nil && !v.Nullable {
-		return nil, e.Error(target.Line, target.Column, target.Lexeme, e.RUNTIME, target.Lexeme+" cannot be set to nil")
+		return nil, nil, e.Error(target.Line, target.Column, target.Lexeme, e.RUNTIME, target.Lexeme+" cannot be set to nil")
 	}
 
+	if v.TypeDefined {
+		fit, ok := fitted(v.Type, newValue)
+		if !ok {
+			return nil, nil, e.Error(target.Line, target.Column, target.Lexeme, e.RUNTIME, fmt.Sprintf("%v does not fit in %s", newValue, typeToString(v.Type)))
+		}
+		newValue = fit
+	}
+
+	newValue, arranged := arrange(v.Type, v.Size, v.Parts, adopt(v.Elem, v.Key, thaw(newValue)))
 	tp := getType(newValue)
-	if v.TypeDefined && v.Type != tp && newValue != nil {
-		return nil, e.Error(target.Line, target.Column, target.Lexeme, e.RUNTIME, "expected type to assign: "+typeToString(v.Type)+", found: "+typeToString(tp))
+	conforms, lacking := conforms(v.Object, v.Trait, newValue)
+	if v.TypeDefined && newValue != nil && (v.Type != tp || !fits(v.Elem, v.Key, newValue) || !arranged || !conforms) {
+		return nil, nil, e.Error(target.Line, target.Column, target.Lexeme, e.RUNTIME, "expected type to assign: "+written(v.Type, v.Elem, v.Key, v.Object, v.Size, v.Parts)+", found: "+describeValue(newValue)+lacks(lacking))
+	}
+
+	if v.Elem == UNKNOWN {
+		v.Elem, v.Key = shape(newValue)
 	}
 
-	v.Type = getType(newValue)
-	v.TypeDefined = true
+	// a nullable variable set to nil keeps the type it holds
+	if newValue != nil {
+		v.Type, v.TypeDefined = tp, true
+	}
 	v.Initialized = true
+	v.Moved = 0
 	v.Value = newValue
-	currentScope.Values[target.Lexeme] = v
 
-	return v.Value, nil
+	return v.Value, nil, nil
+}
+
+// Move gives the value of a variable away, it cannot be read again until something is assigned to it
+func (s *Scope) Move(name l.Token, depth int, slot int, at l.Token) (any, error) {
+	_, v, _, err := s.Get(name, depth, slot)
+	if err != nil {
+		return nil, err
+	}
+
+	scope := s.ancestor(depth)
+	if lock := scope.writing(); lock != nil {
+		defer lock.Unlock()
+	}
+	scope.Values[slot].Value, scope.Values[slot].Moved = nil, at.Line
+	return v, nil
 }
 
 func (s *Scope) Debug() {
 	var prompt string
-	for i, j := range s.Values {
-		prompt = "%s = {Type: %s, Value: %v, TypeDefined: %v, Mutable: %v, Nullable: %v, Initialized: %v}\n"
-		fmt.Printf(prompt, i, typeToString(j.Type), j.Value, j.TypeDefined, j.Mutable, j.Nullable, j.Initialized)
+	for i, j := range s.variables() {
+		prompt = "%d: %s = {Type: %s, Value: %v, TypeDefined: %v, Mutable: %v, Nullable: %v, Initialized: %v}\n"
+		fmt.Printf(prompt, i, j.Name, typeToString(j.Type), j.Value, j.TypeDefined, j.Mutable, j.Nullable, j.Initialized)
 	}
 }
@@ -0,0 +1,64 @@
+package parser
+
+import "fmt"
+
+// Slice grows with push and every element has its Type, or any when Type is UNDEFINED.
+// Copies of a slice share its elements, so a push is seen by all of them.
+// ReadOnly is set on a slice read from an immutable variable, nothing changes through it
+type Slice struct {
+	Type     int
+	Values   *[]any
+	ReadOnly bool
+}
+
+func NewSlice(t int, values []any) Slice {
+	return Slice{Type: t, Values: &values}
+}
+
+// holds tells if a value may be stored in the slice
+func (s Slice) holds(v any) bool {
+	return s.Type == UNDEFINED || getType(v) == s.Type
+}
+
+func (s Slice) Len() int {
+	defer viewing()()
+	return len(*s.Values)
+}
+
+// at reads the element i, which should be in the slice
+func (s Slice) at(i int) any {
+	defer viewing()()
+	return (*s.Values)[i]
+}
+
+// put writes the element i, which should be in the slice
+func (s Slice) put(i int, v any) {
+	release := changing()
+	(*s.Values)[i] = v
+	release()
+	touched(s)
+}
+
+func (s Slice) push(v any) {
+	release := changing()
+	*s.Values = append(*s.Values, v)
+	release()
+	touched(s)
+}
+
+func (s Slice) String() string {
+	values, _ := snapshot(s)
+	return list(values)
+}
+
+// list prints the elements of arrays and slices as `[1, 2]`
+func list(values []any) string {
+	str := "["
+	for i, v := range values {
+		if i > 0 {
+			str += ", "
+		}
+		str += fmt.Sprintf("%v", v)
+	}
+	return str + "]"
+}
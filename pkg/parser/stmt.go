@@ -1,14 +1,62 @@
 package parser
 
-import "github.com/ToniLommez/Neon_Dream_Runner/pkg/lexer"
+import l "github.com/ToniLommez/Neon_Dream_Runner/pkg/lexer"
 
 type Stmt interface {
 	// String() string
 }
 
+// Until inverts the condition, `until x < 0` runs while it is false, Increment
+// is the last part of a `for` and runs after every iteration, even on continue.
+// Every loop has an optional `@label` and Breaks tells if a break or continue aims at it
 type WhileStmt struct {
 	Condition Expr
 	Body      Expr
+	Until     bool
+	Increment Expr
+	Label     string
+	Breaks    bool
+}
+
+// DoStmt checks its condition after the body, `do {} while x` and `do {} until x`
+type DoStmt struct {
+	Body      Expr
+	Condition Expr
+	Until     bool
+	Label     string
+	Breaks    bool
+}
+
+// LoopStmt is `loop`, `loop 50` and `loop 10..50`, Times is nil in the endless loop
+type LoopStmt struct {
+	Keyword l.Token
+	Times   Expr
+	Body    Expr
+	Label   string
+	Breaks  bool
+}
+
+// ForInStmt is `for x in values` and `for i, x in values`, every iteration
+// declares Vars in a scope of its own, Slots is filled by the Resolver
+type ForInStmt struct {
+	Keyword    l.Token
+	Vars       []LetStmt
+	Collection Expr
+	Body       Expr
+	Slots      int
+	Label      string
+	Breaks     bool
+}
+
+// Label is empty for the innermost loop, otherwise the name after `@`
+type BreakStmt struct {
+	Keyword l.Token
+	Label   string
+}
+
+type ContinueStmt struct {
+	Keyword l.Token
+	Label   string
 }
 
 // All `if/else` statements need to have blocks, and all blocks are statements
@@ -20,8 +68,10 @@ type IfStmt struct {
 	Else      Expr
 }
 
+// Slots is how many variables the block declares, filled by the Resolver
 type Block struct {
 	Scope Scope
+	Slots int
 }
 
 type ExprStmt struct {
@@ -32,10 +82,142 @@ type PutStmt struct {
 	Value Expr
 }
 
+// Slot is where the function is stored and Slots is the size of its call scope
+type FnStmt struct {
+	Name     l.Token
+	Mutating bool
+	Params   []Param
+	Returns  []int
+	Body     Expr
+	Slot     int
+	Slots    int
+}
+
+// Elem and Key are the element and key types of a slice, array or map parameter
+// and Object is the name of the obj or trait of an OBJECT parameter, Named binds it.
+// Parts are the written types of what a tuple, a slice, an array or a map parameter holds and Size
+// the one of an array
+type Param struct {
+	Name     l.Token
+	Type     int
+	Elem     int
+	Key      int
+	Object   string
+	Parts    []annotation
+	Size     int
+	Named    Identifier
+	Nullable bool
+	Mutable  bool
+}
+
+// TraitStmt declares the methods an obj needs to be used where the trait is expected, they have no body
+type TraitStmt struct {
+	Name    l.Token
+	Methods []FnStmt
+	Slot    int
+}
+
+// WhenStmt registers a handler for an event, the Handler is a function named after the event
+type WhenStmt struct {
+	Keyword l.Token
+	Handler FnStmt
+}
+
+// TriggerStmt runs every handler of an event with the Args
+type TriggerStmt struct {
+	Keyword l.Token
+	Event   l.Token
+	Args    []Expr
+}
+
+// PulseStmt injects Body into the function Target, before it runs, after it returns or inside it
+// where it marks `@Label`. Function is the name in the declaration of the target and Params how
+// many parameters it has, the Body uses them as its own. Number tells the pulses of the program apart
+type PulseStmt struct {
+	Keyword  l.Token
+	When     l.Token
+	Target   Identifier
+	Label    l.Token
+	Function l.Token
+	Params   int
+	Number   int
+	Body     Expr
+}
+
+// ReactiveStmt is `pulse until` and `pulse while`, the Body runs again every time a variable read
+// by the Condition is written, until the condition holds, or while it holds
+type ReactiveStmt struct {
+	Keyword   l.Token
+	Condition Expr
+	Body      Expr
+	Until     bool
+}
+
+// SpawnStmt is `!> task`, the task runs on its own while the program goes on
+type SpawnStmt struct {
+	Keyword l.Token
+	Task    Expr
+}
+
+// ErrorStmt is `error kind "message"`, `error value` or a bare `error`, raised from where it runs.
+// Results is what the function returns instead, from `error kind => results`
+type ErrorStmt struct {
+	Keyword l.Token
+	Kind    l.Token
+	Message Expr
+	Value   Expr
+	Results Expr
+}
+
+// JoinPoint is an `@label` inside a function where `pulse inside` runs, Function is the name
+// of the enclosing fn and Depth how many scopes up its parameters are
+type JoinPoint struct {
+	Label    l.Token
+	Function l.Token
+	Depth    int
+}
+
+type ReturnStmt struct {
+	Keyword l.Token
+	Value   Expr
+}
+
+type DestructureStmt struct {
+	Lets        []LetStmt
+	Initializer Expr
+}
+
+// Elem and Key are the element and key types of a slice, array or map, UNKNOWN when not written,
+// and Object is the name of the obj or trait of an OBJECT variable, Named binds it. Parts are
+// the written types of what a tuple, a slice, an array or a map holds and Size the one of an array
 type LetStmt struct {
-	Name        lexer.Token
+	Name        l.Token
 	Mutable     bool
 	Nullable    bool
 	Type        int
+	Elem        int
+	Key         int
+	Object      string
+	Parts       []annotation
+	Size        int
+	Named       Identifier
 	Initializer Expr
+	Slot        int
+}
+
+// ObjStmt declares an object, its Fields are lets with the default values as Initializer.
+// Public has the members that may be used from outside the object, Getters and Setters the
+// fields listed in `get` and `set`, which outside code may only read or only assign. Handlers are
+// the `when` of the obj, registered for every instance. Slots is the size of the scope of an
+// instance, which holds `this`, the fields and then the methods
+type ObjStmt struct {
+	Name     l.Token
+	Fields   []LetStmt
+	Methods  []FnStmt
+	Handlers []WhenStmt
+	Public   map[string]bool
+	Getters  map[string]bool
+	Setters  map[string]bool
+	Slot     int
+	Slots    int
 }
@@ -0,0 +1,222 @@
+package parser
+
+import (
+	"fmt"
+	"sync"
+	"sync/atomic"
+
+	e "github.com/ToniLommez/Neon_Dream_Runner/pkg/errutils"
+)
+
+// Tasks follows what `!>` started, so the program can wait for all of them before it ends.
+// Whatever blocks, a channel, a reactive pulse or the program waiting for its tasks, is a waiter
+// until it can go on. When the program and every task are waiters and none of them is ready, nothing
+// will ever wake them: stuck counts these deadlocks and the waiters that see it change fail.
+// The queues of the channels are guarded by mu too, so what makes a waiter ready wakes it through changed
+type Tasks struct {
+	mu      sync.Mutex
+	changed *sync.Cond
+	running int
+	waiters map[*waiter]bool
+	stuck   int
+	errors  []error
+}
+
+// waiter is something blocked until ready, which is called holding the mutex of its Tasks
+type waiter struct {
+	ready func() bool
+}
+
+func NewTasks() *Tasks {
+	t := &Tasks{waiters: make(map[*waiter]bool)}
+	t.changed = sync.NewCond(&t.mu)
+	return t
+}
+
+// alive counts the tasks of every program whose goroutine did not return yet, scopes and collections
+// are only locked while it is not 0
+var alive atomic.Int32
+
+func concurrent() bool {
+	return alive.Load() > 0
+}
+
+// shared guards the elements of arrays and slices and the entries of maps, since every copy of one
+// shares them. Like the scopes it is only taken while a task runs, and never held while user code runs
+var shared sync.RWMutex
+
+// viewing takes shared to read elements, the func it gives releases it
+func viewing() func() {
+	if !concurrent() {
+		return func() {}
+	}
+	shared.RLock()
+	return shared.RUnlock
+}
+
+// changing takes shared to write elements, the func it gives releases it
+func changing() func() {
+	if !concurrent() {
+		return func() {}
+	}
+	shared.Lock()
+	return shared.Unlock
+}
+
+func (s *Scope) tasks() *Tasks {
+	root := s.root()
+	if root.Tasks == nil {
+		root.Tasks = NewTasks()
+	}
+	return root.Tasks
+}
+
+// Go runs the task on its own, what waits for it to end is woken when it does
+func (s *Scope) Go(task func() error) {
+	t := s.tasks()
+	t.mu.Lock()
+	t.running++
+	t.mu.Unlock()
+	alive.Add(1)
+
+	go func() {
+		defer alive.Add(-1)
+		err := task()
+
+		t.mu.Lock()
+		defer t.mu.Unlock()
+		t.running--
+		if err != nil {
+			t.errors = append(t.errors, err)
+		}
+		t.changed.Broadcast()
+		t.check()
+	}()
+}
+
+// Running is how many tasks did not end yet
+func (t *Tasks) Running() int {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	return t.running
+}
+
+// Wait blocks until every task ends, and returns the error of the first one that failed.
+// The tasks blocked in a deadlock fail, so it never waits for them forever
+func (t *Tasks) Wait() error {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	w := &waiter{func() bool { return t.running == 0 }}
+	t.waiters[w] = true
+	t.check()
+	for t.running > 0 {
+		t.changed.Wait()
+	}
+	delete(t.waiters, w)
+
+	if len(t.errors) > 0 {
+		return t.errors[0]
+	}
+	return nil
+}
+
+// check finds a deadlock, the program and every task waiting for something none of them will do.
+// While the program is not waiting it may still do it, so it counts as running
+func (t *Tasks) check() {
+	if len(t.waiters) <= t.running {
+		return
+	}
+	for w := range t.waiters {
+		if w.ready() {
+			return
+		}
+	}
+	t.stuck++
+	t.changed.Broadcast()
+}
+
+// wait blocks holding mu until ready, false when it is part of a deadlock and would never be
+func (t *Tasks) wait(ready func() bool) bool {
+	if ready() {
+		return true
+	}
+	w := &waiter{ready}
+	t.waiters[w] = true
+	defer delete(t.waiters, w)
+
+	stuck := t.stuck
+	for !ready() {
+		t.check()
+		if t.stuck != stuck {
+			return false
+		}
+		t.changed.Wait()
+	}
+	return true
+}
+
+// block is wait for what does not hold mu
+func (t *Tasks) block(ready func() bool) bool {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	return t.wait(ready)
+}
+
+// wake makes the waiters look again if they are ready
+func (t *Tasks) wake() {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	t.changed.Broadcast()
+}
+
+// SpawnEval runs the task on its own. A call has its function and arguments taken before it leaves,
+// as they are right now, and so has a function given without a call, which runs with no arguments.
+// A method is called by the task, as `p.greet` calls a method that takes none
+func (s *Scope) SpawnEval(sp SpawnStmt) (any, error) {
+	var callee any
+	var args []any
+	at := sp.Keyword
+	var err error
+
+	switch task := sp.Task.(type) {
+	case Call:
+		if callee, err = s.evaluate(task.Callee); err != nil {
+			return nil, err
+		}
+		if args, err = s.values(task.Args); err != nil {
+			return nil, err
+		}
+		at = task.Token
+	case Access:
+		s.Go(func() error {
+			_, err := s.evaluate(task)
+			return err
+		})
+		return nil, nil
+	default:
+		if callee, err = s.evaluate(task); err != nil {
+			return nil, err
+		}
+		switch callee.(type) {
+		case Function, Native:
+		default:
+			return nil, e.Error(at.Line, at.Column, at.Lexeme, e.RUNTIME, fmt.Sprintf("!> expects a call or a function, found %v", callee))
+		}
+	}
+
+	switch f := callee.(type) {
+	case Function:
+		s.Go(func() error {
+			_, err := s.call(f, args, at)
+			return err
+		})
+	case Native:
+		s.Go(func() error {
+			_, err := f.Call(args, at)
+			return err
+		})
+	default:
+		return nil, e.Error(at.Line, at.Column, at.Lexeme, e.RUNTIME, fmt.Sprintf("%v is not a function", callee))
+	}
+	return nil, nil
+}
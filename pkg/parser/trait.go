@@ -0,0 +1,115 @@
+package parser
+
+import (
+	"fmt"
+	"strings"
+)
+
+// Trait is the value of a `trait` declaration, any obj with its methods conforms to it
+type Trait struct {
+	Declaration TraitStmt
+}
+
+func (t Trait) String() string {
+	return fmt.Sprintf("<trait %s>", t.Declaration.Name.Lexeme)
+}
+
+// missing lists the methods of a trait that an obj does not provide, or provides with another
+// signature. Private methods do not count, since whoever uses the trait is outside the obj
+func missing(o ObjStmt, t TraitStmt) []string {
+	lacking := make([]string, 0)
+	for _, want := range t.Methods {
+		found := false
+		for _, m := range o.Methods {
+			if m.Name.Lexeme == want.Name.Lexeme {
+				found = o.Public[m.Name.Lexeme] && compatible(m, want)
+				break
+			}
+		}
+		if !found {
+			lacking = append(lacking, signature(want))
+		}
+	}
+	return lacking
+}
+
+// compatible compares a method with the signature a trait asks for, `any` in the trait accepts every type
+// but a parameter declared with ! only matches another declared with !
+func compatible(m FnStmt, want FnStmt) bool {
+	if len(m.Params) != len(want.Params) || len(m.Returns) != len(want.Returns) {
+		return false
+	}
+	for i, p := range want.Params {
+		if (p.Type != UNDEFINED && p.Type != m.Params[i].Type) || p.Mutable != m.Params[i].Mutable {
+			return false
+		}
+	}
+	for i, r := range want.Returns {
+		if r != UNDEFINED && r != m.Returns[i] {
+			return false
+		}
+	}
+	return true
+}
+
+// signature prints a method the way it is declared, as in `greet(STRING) => STRING`
+func signature(f FnStmt) string {
+	str := f.Name.Lexeme
+	if len(f.Params) > 0 {
+		params := make([]string, len(f.Params))
+		for i, p := range f.Params {
+			params[i] = describe(p.Type, p.Elem, p.Key, p.Object)
+			if p.Mutable {
+				params[i] += "!"
+			}
+		}
+		str += "(" + strings.Join(params, ", ") + ")"
+	}
+	if len(f.Returns) > 0 {
+		returns := make([]string, len(f.Returns))
+		for i, r := range f.Returns {
+			returns[i] = typeToString(r)
+		}
+		str += " => " + strings.Join(returns, ", ")
+	}
+	return str
+}
+
+// conforms tells if v is an instance of the named obj, or of an obj with every method of t when the name
+// is a trait, lacking is what it misses. Values that are not instances are left to the other type checks
+func conforms(name string, t *TraitStmt, v any) (ok bool, lacking []string) {
+	i, isInstance := v.(*Instance)
+	if name == "" || !isInstance || i.Name() == name {
+		return true, nil
+	}
+	if t == nil {
+		return false, nil
+	}
+
+	lacking = missing(i.Object.Declaration, *t)
+	return len(lacking) == 0, lacking
+}
+
+// trait reads the trait an annotation names from the slot the Resolver bound it to, nil when it names an obj
+func (s *Scope) trait(named Identifier) *TraitStmt {
+	if named.Name.Lexeme == "" {
+		return nil
+	}
+	scope := s.ancestor(named.Depth)
+	if scope == nil {
+		return nil
+	}
+	v, found := scope.variable(named.Slot)
+	if t, ok := v.Value.(Trait); found && ok {
+		return &t.Declaration
+	}
+	return nil
+}
+
+// lacks ends a type error with the methods a trait misses
+func lacks(lacking []string) string {
+	if len(lacking) == 0 {
+		return ""
+	}
+	return ", missing methods: " + strings.Join(lacking, ", ")
+}
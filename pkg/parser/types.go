@@ -1,6 +1,8 @@
 package parser
 
 import (
+	"fmt"
+	"strings"
 	"unsafe"
 
 	"github.com/ToniLommez/Neon_Dream_Runner/pkg/lexer"
@@ -13,7 +15,24 @@ const (
 	INT
 	UINT
 	FLOAT
+	I8
+	I16
+	I32
+	U8
+	U16
+	U32
+	F32
 	STRING
+	FUNCTION
+	TUPLE
+	RANGE
+	ARRAY
+	SLICE
+	MAP
+	OBJECT
+	CHANNEL
+	ERR
+	POINTER
 	NIL
 	UNDEFINED
 )
@@ -30,8 +49,42 @@ func getType(t any) int {
 		return UINT
 	case float64:
 		return FLOAT
+	case Int8:
+		return I8
+	case Int16:
+		return I16
+	case Int32:
+		return I32
+	case Uint8:
+		return U8
+	case Uint16:
+		return U16
+	case Uint32:
+		return U32
+	case Float32:
+		return F32
 	case string:
 		return STRING
+	case Function, Native:
+		return FUNCTION
+	case Tuple:
+		return TUPLE
+	case Interval:
+		return RANGE
+	case Array:
+		return ARRAY
+	case Slice:
+		return SLICE
+	case Map:
+		return MAP
+	case *Instance:
+		return OBJECT
+	case Channel:
+		return CHANNEL
+	case Err:
+		return ERR
+	case Address:
+		return POINTER
 	case nil:
 		return NIL
 	default:
@@ -45,12 +98,26 @@ func tokenToType(t lexer.Token) int {
 		return BOOL
 	case lexer.CHAR:
 		return CHAR
-	case lexer.INT:
+	case lexer.INT, lexer.I64:
 		return INT
-	case lexer.UINT:
+	case lexer.UINT, lexer.U64:
 		return UINT
-	case lexer.FLOAT:
+	case lexer.FLOAT, lexer.F64:
 		return FLOAT
+	case lexer.I8:
+		return I8
+	case lexer.I16:
+		return I16
+	case lexer.I32:
+		return I32
+	case lexer.U8, lexer.BYTE:
+		return U8
+	case lexer.U16:
+		return U16
+	case lexer.U32:
+		return U32
+	case lexer.F32:
+		return F32
 	case lexer.STRING:
 		return STRING
 	case lexer.NIL:
@@ -76,8 +143,42 @@ func typeToString(t int) string {
 		return "UINT"
 	case FLOAT:
 		return "FLOAT"
+	case I8:
+		return "I8"
+	case I16:
+		return "I16"
+	case I32:
+		return "I32"
+	case U8:
+		return "U8"
+	case U16:
+		return "U16"
+	case U32:
+		return "U32"
+	case F32:
+		return "F32"
 	case STRING:
 		return "STRING"
+	case FUNCTION:
+		return "FUNCTION"
+	case TUPLE:
+		return "TUPLE"
+	case RANGE:
+		return "RANGE"
+	case ARRAY:
+		return "ARRAY"
+	case SLICE:
+		return "SLICE"
+	case MAP:
+		return "MAP"
+	case OBJECT:
+		return "OBJECT"
+	case CHANNEL:
+		return "CHANNEL"
+	case ERR:
+		return "ERR"
+	case POINTER:
+		return "POINTER"
 	case NIL:
 		return "NIL"
 	case UNDEFINED:
@@ -87,6 +188,291 @@ func typeToString(t int) string {
 	}
 }
 
+// zero is the value of the positions of an array that were not given
+func zero(t int) any {
+	switch t {
+	case BOOL:
+		return false
+	case CHAR:
+		return rune(0)
+	case INT:
+		return 0
+	case UINT:
+		return uint(0)
+	case FLOAT:
+		return 0.0
+	case STRING:
+		return ""
+	default:
+		if isSized(t) {
+			return wrap(0, t)
+		}
+		return nil
+	}
+}
+
+// shape returns the element and key types of a slice, an array or a map, the values and direction of a channel
+// and what a pointer points to
+func shape(v any) (int, int) {
+	switch x := v.(type) {
+	case Slice:
+		return x.Type, UNDEFINED
+	case Array:
+		return x.Type, UNDEFINED
+	case Map:
+		return x.Value, x.Key
+	case Channel:
+		return x.Type, x.Direction
+	case Address:
+		return x.Type, UNDEFINED
+	default:
+		return UNDEFINED, UNDEFINED
+	}
+}
+
+// fits tells if the elements and keys of a value are the declared ones, UNKNOWN and UNDEFINED accept anything.
+// A channel given where any direction is declared keeps its own
+func fits(elem int, key int, v any) bool {
+	e, k := shape(v)
+	if _, ok := v.(Channel); ok && key == bidirectional {
+		key = UNDEFINED
+	}
+	return (elem == UNKNOWN || elem == UNDEFINED || elem == e) && (key == UNKNOWN || key == UNDEFINED || key == k)
+}
+
+// arrange checks a value against what its written type tells past Type, Elem and Key: the count
+// and the types of the elements of a tuple, which are fitted in them, the size of an array and the
+// types inside the elements of a collection, as in `[[int]]`. A type written without parts is left alone
+func arrange(t int, size int, parts []annotation, v any) (any, bool) {
+	if len(parts) == 0 {
+		return v, true
+	}
+
+	switch x := v.(type) {
+	case Tuple:
+		if t != TUPLE {
+			return v, true
+		}
+		if len(x) != len(parts) {
+			return v, false
+		}
+		fit := make(Tuple, len(x))
+		for i, part := range parts {
+			var ok bool
+			if fit[i], ok = part.holds(x[i]); !ok {
+				return v, false
+			}
+		}
+		return fit, true
+	case Array, Slice:
+		values, _ := snapshot(x)
+		if a, isArray := x.(Array); isArray && t == ARRAY && len(a.Values) != size {
+			return v, false
+		}
+		return v, len(parts[0].Parts) == 0 || every(values, parts[0])
+	case Map:
+		if len(parts) < 2 || len(parts[0].Parts) == 0 && len(parts[1].Parts) == 0 {
+			return v, true
+		}
+		for _, en := range x.copied() {
+			_, key := parts[0].holds(en.key)
+			_, value := parts[1].holds(en.value)
+			if !key || !value {
+				return v, false
+			}
+		}
+	}
+	return v, true
+}
+
+// every tells if all the values are of a written type
+func every(values []any, a annotation) bool {
+	for _, x := range values {
+		if _, ok := a.holds(x); !ok {
+			return false
+		}
+	}
+	return true
+}
+
+// holds fits a value in a written type, ok is false when the value is not of that type
+func (a annotation) holds(v any) (any, bool) {
+	x, ok := fitted(a.Type, v)
+	if !ok {
+		return v, false
+	}
+	if x == nil && a.Nullable || a.Type == UNDEFINED {
+		return x, true
+	}
+	if x, ok = arrange(a.Type, a.Size, a.Parts, adopt(a.Elem, a.Key, x)); !ok {
+		return v, false
+	}
+	return x, getType(x) == a.Type && fits(a.Elem, a.Key, x)
+}
+
+// adopt gives the declared element type to an empty `[]`, which could not infer one, and to the
+// numbers of a literal when they all fit in a sized type. Channels get the declared direction
+func adopt(elem int, key int, v any) any {
+	if s, ok := v.(Slice); ok && s.Type == UNDEFINED && s.Len() == 0 && elem != UNKNOWN {
+		s.Type = elem
+		return s
+	}
+	if values, ok := snapshot(v); ok && isSized(elem) && getType(v) != TUPLE {
+		if e, _ := shape(v); e == elem {
+			return v
+		}
+		fit := make([]any, len(values))
+		for i, x := range values {
+			if fit[i], ok = fitted(elem, x); !ok || getType(fit[i]) != elem {
+				return v
+			}
+		}
+		if _, isArray := v.(Array); isArray {
+			return Array{Type: elem, Values: fit}
+		}
+		return NewSlice(elem, fit)
+	}
+	if c, ok := v.(Channel); ok {
+		return c.narrow(key)
+	}
+	return v
+}
+
+// freeze marks a collection as read from an immutable variable, so push, delete and
+// element writes reject it, the other values are given back as they are
+func freeze(v any) any {
+	switch x := v.(type) {
+	case Slice:
+		x.ReadOnly = true
+		return x
+	case Array:
+		x.ReadOnly = true
+		return x
+	case Map:
+		x.ReadOnly = true
+		return x
+	default:
+		return v
+	}
+}
+
+// frozen tells if a value is a collection read from an immutable variable
+func frozen(v any) bool {
+	switch x := v.(type) {
+	case Slice:
+		return x.ReadOnly
+	case Array:
+		return x.ReadOnly
+	case Map:
+		return x.ReadOnly
+	default:
+		return false
+	}
+}
+
+// thaw gives a mutable variable its own copy of a frozen collection, so changing it leaves
+// the immutable one alone. The collections inside are still shared, they stay frozen
+func thaw(v any) any {
+	if !frozen(v) {
+		return v
+	}
+	switch x := v.(type) {
+	case Map:
+		own := NewMap(x.Key, x.Value)
+		for _, en := range x.copied() {
+			own.Set(en.key, freeze(en.value))
+		}
+		return own
+	default:
+		shared, _ := snapshot(v)
+		values := make([]any, len(shared))
+		for i, x := range shared {
+			values[i] = freeze(x)
+		}
+		if a, isArray := v.(Array); isArray {
+			return Array{Type: a.Type, Values: values}
+		}
+		return NewSlice(v.(Slice).Type, values)
+	}
+}
+
+// describe prints a type with its elements and keys, as in `[INT]` and `|STRING: INT|`, or the name of its obj
+func describe(t int, elem int, key int, object string) string {
+	switch t {
+	case SLICE:
+		return "[" + typeToString(elem) + "]"
+	case ARRAY:
+		return "[" + typeToString(elem) + ": _]"
+	case MAP:
+		return "|" + typeToString(key) + ": " + typeToString(elem) + "|"
+	case OBJECT:
+		if object != "" {
+			return object
+		}
+		return typeToString(t)
+	case CHANNEL:
+		arrows := map[int]string{bidirectional: "<!>", inbound: "<!", outbound: "!>"}
+		return "chan " + arrows[key] + " " + typeToString(elem)
+	case POINTER:
+		return "*" + describe(elem, UNKNOWN, UNKNOWN, object)
+	default:
+		return typeToString(t)
+	}
+}
+
+// written describes a declared type with the parts it holds and the size of an array
+func written(t int, elem int, key int, object string, size int, parts []annotation) string {
+	return annotation{Type: t, Elem: elem, Key: key, Object: object, Size: size, Parts: parts}.String()
+}
+
+// String prints a written type the way describe does, with the parts it holds and the size of an array
+func (a annotation) String() string {
+	parts := make([]string, len(a.Parts))
+	for i, part := range a.Parts {
+		parts[i] = part.String()
+	}
+
+	switch {
+	case len(parts) == 0:
+		return describe(a.Type, a.Elem, a.Key, a.Object)
+	case a.Type == TUPLE:
+		return "(" + strings.Join(parts, ", ") + ")"
+	case a.Type == SLICE:
+		return "[" + parts[0] + "]"
+	case a.Type == ARRAY:
+		return fmt.Sprintf("[%s: %d]", parts[0], a.Size)
+	case a.Type == MAP && len(parts) == 2:
+		return "|" + parts[0] + ": " + parts[1] + "|"
+	default:
+		return describe(a.Type, a.Elem, a.Key, a.Object)
+	}
+}
+
+// describeValue prints the type of a value the way describe does, a tuple with the types of its elements
+// and an array with its size
+func describeValue(v any) string {
+	switch x := v.(type) {
+	case Tuple:
+		parts := make([]string, len(x))
+		for i, element := range x {
+			parts[i] = describeValue(element)
+		}
+		return "(" + strings.Join(parts, ", ") + ")"
+	case Array:
+		return fmt.Sprintf("[%s: %d]", typeToString(x.Type), len(x.Values))
+	}
+	elem, key := shape(v)
+	return describe(getType(v), elem, key, objectOf(v))
+}
+
+// objectOf is the name of the obj of an instance, empty for other values
+func objectOf(v any) string {
+	if i, ok := v.(*Instance); ok {
+		return i.Name()
+	}
+	return ""
+}
+
 func boolToInt(b bool) int {
 	if b {
 		return 1
@@ -0,0 +1,331 @@
+package vm
+
+import (
+	"fmt"
+
+	c "github.com/ToniLommez/Neon_Dream_Runner/pkg/compiler"
+	e "github.com/ToniLommez/Neon_Dream_Runner/pkg/errutils"
+	l "github.com/ToniLommez/Neon_Dream_Runner/pkg/lexer"
+	p "github.com/ToniLommez/Neon_Dream_Runner/pkg/parser"
+)
+
+// Run executes compiled statements inside env, which is the same kind of scope
+// the tree-walker uses, so both backends share variables, functions and errors
+func Run(f *c.Function, env *p.Scope) (any, error) {
+	return run(&f.Chunk, env)
+}
+
+// Call runs a function value, compiled or not
+func Call(env *p.Scope, f p.Function, args []any, at l.Token) (any, error) {
+	proto, ok := f.Compiled.(*c.Function)
+	if !ok {
+		return env.Call(f, args, at)
+	}
+
+	frame, err := f.Bind(env, args, at)
+	if err != nil {
+		return nil, err
+	}
+	defer frame.End()
+	if err = env.Advise(l.BEFORE, f.Declaration.Name, "", frame); err != nil {
+		return nil, err
+	}
+
+	res, err := f.Settle(run(&proto.Chunk, frame))
+	if err != nil {
+		return nil, err
+	}
+	if err = env.Advise(l.AFTER, f.Declaration.Name, "", frame); err != nil {
+		return nil, err
+	}
+
+	return f.Results(res, at)
+}
+
+func run(chunk *c.Chunk, env *p.Scope) (any, error) {
+	var err error
+	var v any
+
+	code := chunk.Code
+	constants := chunk.Constants
+	stack := make([]any, 0, 16)
+
+	// a return or an error leaves the blocks opened by SCOPE without their END_SCOPE,
+	// they end here as the tree-walker ends them, so the pointers to their variables dangle
+	outer := env
+	var blank *p.Scope
+	var loops []loop
+	defer func() {
+		for ; env != outer; env = env.Parent {
+			env.End()
+		}
+	}()
+
+	pop := func() any {
+		v := stack[len(stack)-1]
+		stack = stack[:len(stack)-1]
+		return v
+	}
+
+	for ip := 0; ip < len(code); ip++ {
+		i := code[ip]
+
+		switch i.Op {
+		case c.CONSTANT:
+			stack = append(stack, constants[i.A])
+		case c.POP:
+			pop()
+		case c.DUP:
+			stack = append(stack, stack[len(stack)-1])
+		case c.GET:
+			if v, ok := env.Peek(i.A, i.B); ok {
+				stack = append(stack, v)
+				continue
+			}
+			_, v, _, err = env.Get(constants[i.C].(l.Token), i.A, i.B)
+			stack = append(stack, v)
+		case c.ASSIGN:
+			a := constants[i.A].(p.Assign)
+			if n, ok := stack[len(stack)-1].(int); ok {
+				if v, ok := env.Poke(a.Depth, a.Slot, a.Operator.Type, n); ok {
+					stack[len(stack)-1] = v
+					continue
+				}
+			}
+			v, err = env.AssignValue(a, pop())
+			stack = append(stack, v)
+		case c.DEFINE:
+			_, err = env.Declare(constants[i.A].(p.LetStmt), pop())
+			stack = append(stack, nil)
+		case c.UNPACK:
+			_, err = env.Unpack(constants[i.A].(p.DestructureStmt), pop())
+			stack = append(stack, nil)
+		case c.FUNCTION:
+			proto := constants[i.A].(*c.Function)
+			_, err = env.DeclareFunction(p.Function{Declaration: proto.Declaration, Closure: env, Compiled: proto})
+			stack = append(stack, nil)
+		case c.CLOSURE:
+			proto := constants[i.A].(*c.Function)
+			stack = append(stack, p.Function{Declaration: proto.Declaration, Closure: env, Compiled: proto})
+		case c.BINARY:
+			r := pop()
+			left := pop()
+			op := constants[i.A].(l.Token)
+			if x, ok := integers(op.Type, left, r); ok {
+				stack = append(stack, x)
+				continue
+			}
+			v, err = p.BinaryOp(op, left, r)
+			stack = append(stack, v)
+		case c.UNARY:
+			v, err = p.UnaryOp(constants[i.A].(l.Token), pop())
+			stack = append(stack, v)
+		case c.CAST:
+			t := pop()
+			v, err = p.CastOp(constants[i.A].(l.Token), pop(), t)
+			stack = append(stack, v)
+		case c.TRUTHY:
+			v, err = p.Truthy(pop())
+			stack = append(stack, v)
+		case c.JUMP:
+			ip = i.A - 1
+		case c.JUMP_IF_FALSE, c.JUMP_IF_TRUE:
+			x := pop()
+			b, ok := x.(bool)
+			if !ok {
+				b, err = p.Truthy(x)
+			}
+			if err == nil && b == (i.Op == c.JUMP_IF_TRUE) {
+				ip = i.A - 1
+			}
+		case c.SCOPE:
+			// a block without variables keeps nothing of its own, the body of a loop opens the same one again
+			if i.A == 0 && blank != nil && blank.Parent == env {
+				env = blank
+				continue
+			}
+			env = &p.Scope{Values: make([]p.Variable, 0, i.A), Parent: env}
+			if i.A == 0 {
+				blank = env
+			}
+		case c.END_SCOPE:
+			env.End()
+			env = env.Parent
+		case c.CALL:
+			at := constants[i.B].(l.Token)
+			args := popped(&stack, i.A)
+
+			switch f := pop().(type) {
+			case p.Function:
+				v, err = Call(env, f, args, at)
+			case p.Native:
+				v, err = f.Call(args, at)
+			default:
+				return nil, e.Error(at.Line, at.Column, at.Lexeme, e.RUNTIME, fmt.Sprintf("%v is not a function", f))
+			}
+			stack = append(stack, v)
+		case c.RETURN:
+			return pop(), nil
+		case c.TUPLE:
+			values := popped(&stack, i.A)
+			stack = append(stack, p.Tuple(values))
+		case c.PUT:
+			p.PutValue(pop())
+			stack = append(stack, nil)
+		case c.EVAL:
+			v, err = env.Evaluate(constants[i.A])
+
+			// a `=>` inside a node left to the tree-walker still leaves this function
+			if r, ok := p.IsReturn(err); ok {
+				return r, nil
+			}
+			stack = append(stack, v)
+		case c.LOOP:
+			loops = append(loops, loop{Loop: constants[i.A].(c.Loop), height: len(stack), env: env})
+		case c.END_LOOP:
+			loops = loops[:len(loops)-1]
+		case c.ITER:
+			v, err = p.Iterate(constants[i.A].(l.Token), pop())
+			stack = append(stack, v)
+		case c.NEXT:
+			index, value, ok, failed := stack[len(stack)-1].(p.Cursor)()
+			if err = failed; err == nil && !ok {
+				ip = i.A - 1
+				continue
+			}
+			switch i.B {
+			case 1:
+				stack = append(stack, value)
+			case 2:
+				stack = append(stack, value, index)
+			}
+		case c.MATCH:
+			var matched bool
+			if matched, err = env.Matches(constants[i.B].([]p.Expr), stack[len(stack)-1]); err == nil && !matched {
+				ip = i.A - 1
+			}
+		case c.INDEX:
+			pos := pop()
+			v, err = env.Position(constants[i.A].(p.PositionAccess), pop(), pos)
+			stack = append(stack, v)
+		case c.MEMBER:
+			// a method that takes nothing is called without its parentheses, `p.greet` is `p.greet()`
+			a := constants[i.A].(p.Access)
+			var skipped bool
+			if v, skipped, err = env.Member(a, pop()); skipped {
+				stack = append(stack, nil)
+				ip = i.B - 1
+				continue
+			}
+			if f, ok := v.(p.Function); ok && f.Arity() == 0 {
+				if _, isCall := a.Right.(p.Call); !isCall {
+					v, err = Call(env, f, nil, constants[i.C].(l.Token))
+				}
+			}
+			stack = append(stack, v)
+		case c.SET_INDEX:
+			v, err = env.SetPosition(constants[i.A].(p.PositionAssign), pop())
+			stack = append(stack, v)
+		case c.SET_FIELD:
+			v, err = env.SetField(constants[i.A].(p.AccessAssign), pop())
+			stack = append(stack, v)
+		case c.ARRAY:
+			values := popped(&stack, i.B)
+			v, err = constants[i.A].(p.ArrayLiteral).Build(pop(), values)
+			stack = append(stack, v)
+		case c.SLICE:
+			v, err = constants[i.A].(p.SliceLiteral).Build(popped(&stack, i.B))
+			stack = append(stack, v)
+		case c.MAP:
+			v, err = constants[i.A].(p.MapLiteral).Build(popped(&stack, 2*i.B))
+			stack = append(stack, v)
+		default:
+			return nil, e.Error(0, 0, "", e.RUNTIME, fmt.Sprintf("unknown instruction: %v", i.Op))
+		}
+
+		if err != nil {
+			label, stop, ok := p.IsLoop(err)
+			n := len(loops) - 1
+			for ok && n >= 0 && label != "" && loops[n].Label != label {
+				n--
+			}
+			if !ok || n < 0 {
+				return nil, err
+			}
+
+			// a break or continue leaves the blocks and the values the loop opened since it started
+			to := loops[n]
+			for loops = loops[:n+1]; env != to.env; env = env.Parent {
+				env.End()
+			}
+			stack = stack[:to.height]
+			ip = to.Continue - 1
+			if stop {
+				ip = to.Break - 1
+			}
+			err = nil
+		}
+	}
+
+	return nil, nil
+}
+
+// popped takes the n values on top of the stack, in the order they were pushed
+func popped(stack *[]any, n int) []any {
+	values := make([]any, n)
+	copy(values, (*stack)[len(*stack)-n:])
+	*stack = (*stack)[:len(*stack)-n]
+	return values
+}
+
+// loop is a loop entered by LOOP, with the height of the stack and the scope it started with
+type loop struct {
+	c.Loop
+	height int
+	env    *p.Scope
+}
+
+// integers is the shortcut for the most common case of numeric loops, anything
+// it does not handle goes to p.BinaryOp, which gives the same answer slower
+func integers(op l.TokenType, left any, right any) (any, bool) {
+	a, ok := left.(int)
+	if !ok {
+		return nil, false
+	}
+	b, ok := right.(int)
+	if !ok {
+		return nil, false
+	}
+
+	// an overflow or a division by zero is left to p.BinaryOp, which raises the error
+	switch op {
+	case l.PLUS, l.MINUS, l.STAR:
+		n, ok := p.IntOp(op, a, b)
+		return n, ok
+	case l.SLASH:
+		if b == 0 {
+			return nil, false
+		}
+		n, ok := p.IntOp(op, a, b)
+		return n, ok
+	case l.MOD:
+		if b == 0 {
+			return nil, false
+		}
+		return a % b, true
+	case l.LESS:
+		return a < b, true
+	case l.LESS_EQUAL:
+		return a <= b, true
+	case l.GREATER:
+		return a > b, true
+	case l.GREATER_EQUAL:
+		return a >= b, true
+	case l.EQUAL:
+		return a == b, true
+	case l.NOT_EQUAL:
+		return a != b, true
+	default:
+		return nil, false
+	}
+}